@@ -1,76 +1,1167 @@
 package main
 
 import (
+	"SnakeGameGolang/internal/achievements"
 	cls "SnakeGameGolang/internal/clearscreen"
+	"SnakeGameGolang/internal/daily"
+	"SnakeGameGolang/internal/editor"
+	"SnakeGameGolang/internal/highscores"
+	"SnakeGameGolang/internal/levelgen"
+	"SnakeGameGolang/internal/profiles"
 	sg "SnakeGameGolang/internal/snakegame"
+	"SnakeGameGolang/internal/sshserver"
+	"SnakeGameGolang/internal/terminal"
+	"SnakeGameGolang/internal/webserver"
+	"bufio"
+	"cmp"
+	"flag"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/eiannone/keyboard"
 )
 
-var (
-	displayFunc sg.DisplayFunc = func(board [][]sg.Cell, score int) {
-		cls.ClearScreen()
-		fmt.Printf("\t<Score: %d>\n", score)
-		for hight := range board {
-			for widht := range board[hight] {
-				switch board[hight][widht] {
-				case sg.CellEmpty:
-					fmt.Print("_")
-				case sg.CellFood:
-					fmt.Print("$")
-				case sg.CellSnakeHead:
-					fmt.Print("%")
-				case sg.CellSnakeTail:
-					fmt.Print("*")
-				}
+// Read the keyboard and send the commands, the bindings come before the default keys
+func newKeyHandler(bindings map[rune]sg.Command) sg.KeyHandlerFunc {
+	return func(commands chan<- sg.Command) {
+		keysEvents, err := keyboard.GetKeys(10)
+		if err != nil {
+			panic(err)
+		}
+		defer func() {
+			_ = keyboard.Close()
+		}()
+
+		for {
+			event := <-keysEvents
+			if event.Err != nil {
+				panic(event.Err)
+			}
+
+			command, ok := boundCommand(bindings, event)
+			if !ok {
+				continue
 			}
-			fmt.Println()
+			commands <- command
+			if command.Kind == sg.CommandQuit {
+				return
+			}
+		}
+	}
+}
+
+// Game command of the key, the profile bindings come before the default keys
+func boundCommand(bindings map[rune]sg.Command, event keyboard.KeyEvent) (sg.Command, bool) {
+	if command, ok := bindings[event.Rune]; ok && event.Key == 0 {
+		return command, true
+	}
+	return keyCommand(event)
+}
+
+// Game command bound to the key, false if there is none
+func keyCommand(event keyboard.KeyEvent) (sg.Command, bool) {
+	switch event.Key {
+	case keyboard.KeyArrowUp:
+		return sg.Turn(sg.DirectionUp), true
+	case keyboard.KeyArrowRight:
+		return sg.Turn(sg.DirectionRight), true
+	case keyboard.KeyArrowDown:
+		return sg.Turn(sg.DirectionDown), true
+	case keyboard.KeyArrowLeft:
+		return sg.Turn(sg.DirectionLeft), true
+
+	case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+		return sg.Command{Kind: sg.CommandRewind}, true
+	case keyboard.KeySpace:
+		return sg.Command{Kind: sg.CommandResume}, true
+
+	case keyboard.KeyEsc:
+		return sg.Command{Kind: sg.CommandQuit}, true
+	}
+
+	// Diagonals of the diagonal and hex grids, then the second player of the light-cycle mode
+	switch event.Rune {
+	case 'q':
+		return sg.Turn(sg.DirectionUpLeft), true
+	case 'e':
+		return sg.Turn(sg.DirectionUpRight), true
+	case 'z':
+		return sg.Turn(sg.DirectionDownLeft), true
+	case 'c':
+		return sg.Turn(sg.DirectionDownRight), true
+
+	case 'w':
+		return sg.Command{Kind: sg.CommandTurn, Direction: sg.DirectionUp, Player: 1}, true
+	case 'd':
+		return sg.Command{Kind: sg.CommandTurn, Direction: sg.DirectionRight, Player: 1}, true
+	case 's':
+		return sg.Command{Kind: sg.CommandTurn, Direction: sg.DirectionDown, Player: 1}, true
+	case 'a':
+		return sg.Command{Kind: sg.CommandTurn, Direction: sg.DirectionLeft, Player: 1}, true
+	}
+	return sg.Command{}, false
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "ssh-serve":
+			sshServe(os.Args[2:])
+			return
+		case "daily":
+			playDaily(os.Args[2:])
+			return
+		case "verify":
+			verify(os.Args[2:])
+			return
+		case "tron":
+			playLightCycles(os.Args[2:])
+			return
+		case "puzzle":
+			playPuzzle(os.Args[2:])
+			return
+		case "edit":
+			editLevel(os.Args[2:])
+			return
+		case "generate":
+			generateLevel(os.Args[2:])
+			return
+		case "achievements":
+			listAchievements(os.Args[2:])
+			return
+		case "profile":
+			editProfile(os.Args[2:])
+			return
+		}
+	}
+
+	hight := flag.Int("hight", 15, "board hight")
+	width := flag.Int("width", 15, "board width")
+	viewport := flag.Int("viewport", 0, "visible area side on large boards, 0 selects the default")
+	savePath := flag.String("save", "snake.save", "file to save the game to on Esc")
+	resume := flag.Bool("resume", false, "resume the game from the save file")
+	autosave := flag.Int("autosave", 50, "autosave every N ticks, 0 disables")
+	levelPath := flag.String("level", "", "level file to play")
+	generate := flag.String("generate", "", "play a generated level: maze, prim, caves or arena")
+	generateSeed := flag.Int64("generate-seed", 0, "seed of the generated level, 0 picks a random one")
+	portals := flag.Int("portals", 0, "number of random portal pairs")
+	balls := flag.Int("balls", 0, "number of deadly bouncing balls")
+	chasers := flag.Int("chasers", 0, "number of chasers shrinking the snake")
+	topologyName := flag.String("topology", "torus", "border behavior: torus, walled, bounce, klein or top,right,bottom,left rules of wrap, kill, bounce, mirror")
+	gridName := flag.String("grid", "square", "board grid: square, diagonal (Q, E, Z, C turn diagonally) or hex (Up and Down keep the heading side)")
+	food := flag.String("food", "uniform", "food placement: uniform, distance, edges or fair")
+	foodDistance := flag.Int("food-distance", 5, "minimal food distance from the head for the distance placement")
+	grow := flag.Int("grow", 1, "segments to grow per food")
+	noSelfCollision := flag.Bool("no-self-collision", false, "crawl through the own body")
+	mirror := flag.Bool("mirror", false, "mirror the controls")
+	speedUp := flag.Int("speed-up", 0, "speed up by 10% every N foods, 0 disables")
+	powerUps := flag.Int("power-ups", 0, "spawn a power-up every N ticks, 0 disables")
+	powerUpDuration := flag.Int("power-up-duration", 5*sg.TicksPerSecond, "ticks a collected power-up lasts")
+	modeName := flag.String("mode", "classic", "game mode: classic, time-attack or survival")
+	timeLimit := flag.Duration("time-limit", 60*time.Second, "game time of the time-attack mode")
+	timePenalty := flag.Duration("time-penalty", 5*time.Second, "game time lost per death in the time-attack mode")
+	collapseEvery := flag.Int("collapse-every", 10*sg.TicksPerSecond, "ticks between border ring collapses in the survival mode")
+	collapseWarning := flag.Int("collapse-warning", 3*sg.TicksPerSecond, "ticks the next collapse is shown in advance in the survival mode")
+	scoresPath := flag.String("scores", "snake.scores", "file to keep the high scores in")
+	turnBased := flag.Bool("turn-based", false, "advance only on arrow keys, Backspace undoes moves")
+	practice := flag.Int("practice", 0, "practice mode keeping N seconds to rewind with Backspace, Space resumes")
+	seed := flag.Int64("seed", 0, "game seed, runs on a fixed seed are recorded and can be raced against, 0 picks a random one")
+	ghost := flag.Bool("ghost", false, "race against the ghost of the best run with the same seed and settings")
+	replaysPath := flag.String("replays", "snake.replays", "file to keep the best runs for the ghost in")
+	profileName := flag.String("profile", "", "player profile, created if missing, a picker is shown if empty")
+	flag.StringVar(profileName, "player", "", "same as -profile, kept for older scripts")
+	profilesPath := flag.String("profiles", "snake.profiles", "file to keep the player profiles in")
+	flag.Parse()
+
+	options := []sg.Option{
+		sg.WithViewport(*viewport, *viewport),
+		sg.WithAutosave(*autosave, func(game *sg.SnakeGame) {
+			_ = saveGame(game, *savePath)
+		}),
+	}
+	topology, err := sg.ParseEdgeTopology(*topologyName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	options = append(options, sg.WithTopology(topology))
+
+	grid, err := sg.ParseGrid(*gridName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	options = append(options, sg.WithGrid(grid))
+
+	spawner, err := sg.FoodConfig{Strategy: *food, MinDistance: *foodDistance}.Spawner()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	options = append(options, sg.WithFoodSpawner(spawner))
+
+	rules := []sg.Rule{sg.GrowOnEat{Segments: *grow}, sg.ScoreOnEat{Points: 1}}
+	if *noSelfCollision {
+		rules = append(rules, sg.NoSelfCollision{})
+	}
+	if *mirror {
+		rules = append(rules, sg.MirrorControls{})
+	}
+	if *speedUp > 0 {
+		rules = append(rules, sg.SpeedUp{Foods: *speedUp, Percent: 10, Min: time.Second / 20})
+	}
+	options = append(options, sg.WithRules(rules...))
+
+	mode, err := sg.ModeConfig{
+		Name:     *modeName,
+		Duration: *timeLimit,
+		Penalty:  *timePenalty,
+		Every:    *collapseEvery,
+		Warning:  *collapseWarning,
+	}.Mode()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	options = append(options, sg.WithMode(mode))
+
+	if *portals > 0 {
+		options = append(options, sg.WithRandomPortals(*portals))
+	}
+	if *powerUps > 0 {
+		options = append(options, sg.WithPowerUps(sg.PowerUpConfig{Every: *powerUps, Lifetime: *powerUps / 2, Duration: *powerUpDuration}))
+	}
+	if *balls > 0 || *chasers > 0 {
+		options = append(options, sg.WithRandomEnemies(*balls, *chasers))
+	}
+	if *levelPath != "" && *generate != "" {
+		fmt.Println("Expected either a level file or a generated level")
+		os.Exit(2)
+	}
+	if *levelPath != "" {
+		level, err := loadLevel(*levelPath)
+		if err != nil {
+			fmt.Printf("Failed to load level: %v\n", err)
+			os.Exit(1)
+		}
+		options = append(options, sg.WithLevel(level))
+	}
+	if *generate != "" {
+		if *generateSeed == 0 {
+			*generateSeed = time.Now().UnixNano()
+		}
+		level, err := levelgen.Generate(levelgen.Settings{Kind: *generate, Hight: *hight, Width: *width, Seed: *generateSeed, Players: 2, Runway: min(3, min(*hight, *width)/2-1)})
+		if err != nil {
+			fmt.Printf("Failed to generate the level: %v\n", err)
+			os.Exit(2)
 		}
+		options = append(options, sg.WithLevel(level))
+	}
+	if *practice > 0 {
+		options = append(options, sg.WithPractice(*practice*sg.TicksPerSecond))
+	}
+	if *turnBased {
+		options = append(options, sg.WithTurnBased(1000))
 	}
 
-	keyHandlerFunc sg.KeyHandlerFunc = func(quit chan bool, turn chan sg.Direction) {
-		{
-			keysEvents, err := keyboard.GetKeys(10)
-			if err != nil {
-				panic(err)
+	// Only complete runs on a fixed seed can be replayed
+	recording := *seed != 0 && *practice == 0 && !*resume
+	if *ghost && !recording {
+		fmt.Println("Racing a ghost needs a -seed and a new game without practice")
+		os.Exit(2)
+	}
+	var replays *highscores.Replays
+	setup := raceSetup()
+	if *seed != 0 {
+		options = append(options, sg.WithSeed(*seed))
+	}
+	if recording {
+		options = append(options, sg.WithRecording())
+		if replays, err = loadReplays(*replaysPath); err != nil {
+			fmt.Printf("Failed to load the replays: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *ghost {
+		best, ok := replays.Best(setup)
+		if !ok {
+			fmt.Println("No run recorded with this seed and settings yet, play one first")
+			os.Exit(1)
+		}
+		options = append(options, sg.WithGhost(best.Log))
+	}
+
+	store, err := loadProfiles(*profilesPath)
+	if err != nil {
+		fmt.Printf("Failed to load the profiles: %v\n", err)
+		os.Exit(1)
+	}
+	profile := store.Get(*profileName)
+	if profile == nil && *profileName != "" {
+		profile, err = store.Create(*profileName)
+	} else if profile == nil {
+		profile, err = pickProfile(store, os.Stdin)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	store.Last = profile.Name
+
+	// Rewinding practice and turn-based games could unlock anything and inflate the stats,
+	// a resumed game may turn out to be one of them
+	snakeGame := sg.SnakeGame{}
+	tracker := achievements.NewTracker(profile.Achievements)
+	options = append(options, sg.WithEventHandler(func(event sg.Event) {
+		if !snakeGame.Rewindable() {
+			profile.Stats.Handle(event)
+			tracker.Handle(event)
+		}
+	}))
+
+	renderer, bindings := profileControls(profile)
+	display := func(board [][]sg.Cell, score int, hud sg.HUD) {
+		hud.Toasts = tracker.Toasts()
+		renderer.Display(board, score, hud)
+	}
+	snakeGame.Init(*hight, *width, false, display, newKeyHandler(bindings), options...)
+	if *resume {
+		if err := loadGame(&snakeGame, *savePath); err != nil {
+			fmt.Printf("Failed to resume: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	boardHight, boardWidth := snakeGame.Size()
+	tracker.SetGame(achievements.Game{Hight: boardHight, Width: boardWidth, TurnBased: snakeGame.IsTurnBased()})
+	if err := snakeGame.CheckGrid(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	renderer.UseGrid(snakeGame.Grid())
+	result := snakeGame.Run()
+
+	// Game over
+	renderer.GameOver(result)
+	if snakeGame.IsPractice() {
+		fmt.Println("Practice game, the score is not ranked")
+	} else if result.Outcome != sg.OutcomeQuit {
+		// Turn-based games are ranked apart from the timed ones
+		mode := snakeGame.ModeName()
+		if snakeGame.IsTurnBased() {
+			mode += "/turn-based"
+		}
+		if err := recordScore(*scoresPath, mode, result.Score); err != nil {
+			fmt.Printf("Failed to record the score: %v\n", err)
+		}
+		if profile.Scores.Add(mode, highscores.Entry{Score: result.Score, Date: time.Now()}) == 1 {
+			fmt.Printf("New personal %s high score for %s!\n", mode, profile.Name)
+		}
+	}
+	if !snakeGame.Rewindable() && result.Outcome != sg.OutcomeQuit {
+		profile.Stats.Record(result)
+	}
+
+	if recording && result.Outcome != sg.OutcomeQuit {
+		if replays.Add(setup, highscores.Replay{Score: result.Score, Date: time.Now(), Log: snakeGame.InputLog()}) {
+			fmt.Println("New personal best on this seed, race it with -ghost")
+			if err := writeFile(*replaysPath, replays.Save); err != nil {
+				fmt.Printf("Failed to save the replay: %v\n", err)
 			}
-			defer func() {
-				_ = keyboard.Close()
-			}()
-
-			for {
-				event := <-keysEvents
-				if event.Err != nil {
-					panic(event.Err)
+		}
+	}
+
+	for _, a := range tracker.Unlocked {
+		fmt.Printf("Achievement unlocked: %s - %s\n", a.Title, a.Description)
+	}
+	if err := writeFile(*profilesPath, store.Save); err != nil {
+		fmt.Printf("Failed to save the profile: %v\n", err)
+	}
+
+	// Keep the game for later if quit, otherwise there is nothing to resume
+	if result.Outcome != sg.OutcomeQuit {
+		_ = os.Remove(*savePath)
+	} else if err := saveGame(&snakeGame, *savePath); err != nil {
+		fmt.Printf("Failed to save: %v\n", err)
+	}
+}
+
+// Write the game state to the file, replacing it atomically
+func saveGame(game *sg.SnakeGame, path string) error {
+	return writeFile(path, game.Save)
+}
+
+// Write the file through a temporary one, replacing it atomically
+func writeFile(path string, write func(w io.Writer) error) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Rank the score among the ones of the same mode and print the place
+func recordScore(path string, mode string, score int) error {
+	table, err := loadScores(path)
+	if err != nil {
+		return err
+	}
+
+	best, ranked := table.Best(mode)
+	place := table.Add(mode, highscores.Entry{Score: score, Date: time.Now()})
+	switch {
+	case place == 1:
+		fmt.Printf("New %s high score!\n", mode)
+	case place > 0:
+		fmt.Printf("Place %d in %s, the best is %d\n", place, mode, best.Score)
+	case ranked:
+		fmt.Printf("The best %s score is %d\n", mode, best.Score)
+	}
+	return writeFile(path, table.Save)
+}
+
+// Read the high scores, a missing file gives an empty table
+func loadScores(path string) (*highscores.Table, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return highscores.Load(strings.NewReader(""))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return highscores.Load(file)
+}
+
+// Game settings of the command line, runs with the same ones can race each other
+func raceSetup() string {
+	var settings []string
+	flag.VisitAll(func(f *flag.Flag) {
+		switch f.Name {
+		case "viewport", "save", "resume", "autosave", "scores", "ghost", "replays", "profile", "player", "profiles":
+			return
+		}
+		settings = append(settings, "-"+f.Name+"="+f.Value.String())
+	})
+	return strings.Join(settings, " ")
+}
+
+// Read the best runs, a missing file gives none
+func loadReplays(path string) (*highscores.Replays, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return highscores.LoadReplays(strings.NewReader(""))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return highscores.LoadReplays(file)
+}
+
+// Read the player profiles, a missing file gives none. The achievements file of the releases
+// before the profiles is imported from the same directory, it's kept for older binaries
+func loadProfiles(path string) (*profiles.Store, error) {
+	store, err := readProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := os.Open(filepath.Join(filepath.Dir(path), "snake.achievements"))
+	if err != nil {
+		return store, nil
+	}
+	defer legacy.Close()
+	if err := store.ImportAchievements(legacy); err != nil {
+		fmt.Printf("Failed to import the old achievements: %v\n", err)
+	}
+	return store, nil
+}
+
+// Read the profiles file, a missing file gives none
+func readProfiles(path string) (*profiles.Store, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return profiles.Load(strings.NewReader(""))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return profiles.Load(file)
+}
+
+// Renderer in the theme of the profile and its key bindings, the defaults without a profile
+func profileControls(profile *profiles.Profile) (*terminal.Renderer, map[rune]sg.Command) {
+	renderer := terminal.NewRenderer(os.Stdout, cls.ClearScreenTo, "\n")
+	if profile == nil {
+		return renderer, nil
+	}
+	if err := renderer.SetTheme(profile.Theme); err != nil {
+		fmt.Printf("Profile %s: %v, using the default one\n", profile.Name, err)
+	}
+	bindings, _ := profile.Bindings()
+	return renderer, bindings
+}
+
+// Ask who is playing until an existing profile is picked or a new one is created,
+// an empty answer picks the last played profile
+func pickProfile(store *profiles.Store, in io.Reader) (*profiles.Profile, error) {
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Println("Who is playing?")
+		for i, p := range store.Profiles {
+			fmt.Printf("  %d) %s\n", i+1, p.Name)
+		}
+		last := store.Get(store.Last)
+		if last != nil {
+			fmt.Printf("Number or a new name, Enter for %s: ", last.Name)
+		} else {
+			fmt.Print("Number or a new name: ")
+		}
+
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("no profile picked: %w", err)
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" && last != nil {
+			return last, nil
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(store.Profiles) {
+			return store.Profiles[n-1], nil
+		}
+		if p := store.Get(answer); p != nil {
+			return p, nil
+		}
+		p, err := store.Create(answer)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("Profile %s created\n", p.Name)
+		return p, nil
+	}
+}
+
+// Read the level file
+func loadLevel(path string) (*sg.Level, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return sg.LoadLevel(file)
+}
+
+// Read the puzzle pack file
+func loadPuzzlePack(path string) (*sg.PuzzlePack, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return sg.LoadPuzzlePack(file)
+}
+
+// Restore the game state from the file
+func loadGame(game *sg.SnakeGame, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return game.Load(file)
+}
+
+// Host the browser frontend
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	hight := flags.Int("hight", 15, "board hight")
+	width := flags.Int("width", 15, "board width")
+	borderKiller := flags.Bool("border-killer", false, "die when hitting the border")
+	_ = flags.Parse(args)
+
+	fmt.Printf("Serving snake on http://%s\n", *addr)
+	err := webserver.ListenAndServe(*addr, webserver.Settings{
+		BoardHight:   *hight,
+		BoardWidth:   *width,
+		BorderKiller: *borderKiller,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Host a game per SSH session
+func sshServe(args []string) {
+	flags := flag.NewFlagSet("ssh-serve", flag.ExitOnError)
+	addr := flags.String("addr", ":2222", "address to listen on")
+	hostKey := flags.String("host-key", "snake_host_key", "host key file, generated if missing")
+	hight := flags.Int("hight", 15, "board hight")
+	width := flags.Int("width", 15, "board width")
+	borderKiller := flags.Bool("border-killer", false, "die when hitting the border")
+	_ = flags.Parse(args)
+
+	fmt.Printf("Serving snake over ssh on %s\n", *addr)
+	err := sshserver.ListenAndServe(sshserver.Settings{
+		Addr:         *addr,
+		HostKeyPath:  *hostKey,
+		BoardHight:   *hight,
+		BoardWidth:   *width,
+		BorderKiller: *borderKiller,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Play the light-cycle mode, the second player steers with WASD
+func playLightCycles(args []string) {
+	flags := flag.NewFlagSet("tron", flag.ExitOnError)
+	hight := flags.Int("hight", 20, "board hight")
+	width := flags.Int("width", 40, "board width")
+	players := flags.Int("players", 1, "human players, 1 or 2")
+	bots := flags.Int("bots", 1, "computer-controlled cycles")
+	topologyName := flags.String("topology", "walled", "border behavior, see the main flags")
+	levelPath := flags.String("level", "", "level file with a spawn for every cycle, its size replaces -hight and -width")
+	arena := flags.Bool("arena", false, "play on a generated symmetric arena, for 2 or 4 cycles")
+	savePath := flags.String("save", "tron.save", "file to save the game to on Esc")
+	resume := flags.Bool("resume", false, "resume the game from the save file")
+	profileName := flags.String("profile", "", "profile with the theme and key bindings of the first player, the last played one if empty")
+	profilesPath := flags.String("profiles", "snake.profiles", "file the player profiles are kept in")
+	_ = flags.Parse(args)
+
+	topology, err := sg.ParseEdgeTopology(*topologyName)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if *levelPath != "" && *arena {
+		fmt.Println("Expected either a level file or a generated arena")
+		os.Exit(2)
+	}
+	var level *sg.Level
+	if *levelPath != "" {
+		if level, err = loadLevel(*levelPath); err != nil {
+			fmt.Printf("Failed to load level: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *arena {
+		level, err = levelgen.Generate(levelgen.Settings{Kind: "arena", Hight: *hight, Width: *width, Seed: time.Now().UnixNano(), Players: *players + *bots, Runway: min(3, min(*hight, *width)/2-1)})
+		if err != nil {
+			fmt.Printf("Failed to generate the level: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *players < 1 || *players > 2 || *bots < 0 || level == nil && *players+*bots > *width {
+		fmt.Println("Expected 1 or 2 players and enough board width for the bots")
+		os.Exit(2)
+	}
+	if level != nil && len(level.Spawns) < *players+*bots {
+		fmt.Printf("The level has spawns for %d cycles\n", len(level.Spawns))
+		os.Exit(2)
+	}
+	cycleBots := make([]sg.Bot, *bots)
+	for i := range cycleBots {
+		cycleBots[i] = sg.SpaceBot{Depth: 64}
+	}
+
+	options := []sg.Option{sg.WithTopology(topology)}
+	if level != nil {
+		options = append(options, sg.WithLevel(level))
+	}
+	renderer, bindings := profileControls(lookupProfile(*profilesPath, *profileName))
+	game := sg.SnakeGame{}
+	game.Init(*hight, *width, false, renderer.Display, newKeyHandler(bindings), append(options,
+		sg.WithRules(sg.Trail{}),
+		sg.WithFoodSpawner(sg.NoFood{}),
+		sg.WithRivals(*players-1, cycleBots...))...)
+	if *resume {
+		if err := loadGame(&game, *savePath); err != nil {
+			fmt.Printf("Failed to resume: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := game.CheckGrid(); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	renderer.UseGrid(game.Grid())
+	result := game.Run()
+	renderer.CyclesOver(result, game.Standings())
+
+	// Keep the game for later if quit, otherwise there is nothing to resume
+	if result.Outcome != sg.OutcomeQuit {
+		_ = os.Remove(*savePath)
+	} else if err := saveGame(&game, *savePath); err != nil {
+		fmt.Printf("Failed to save: %v\n", err)
+	}
+}
+
+// Play a puzzle of the pack turn by turn, or prove that every puzzle of it is solvable
+func playPuzzle(args []string) {
+	flags := flag.NewFlagSet("puzzle", flag.ExitOnError)
+	check := flags.Bool("check", false, "verify the known solutions and solve every puzzle instead of playing")
+	profileName := flags.String("profile", "", "profile with the theme and key bindings, the last played one if empty")
+	profilesPath := flags.String("profiles", "snake.profiles", "file the player profiles are kept in")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 || flags.NArg() > 2 {
+		fmt.Println("Usage: snake puzzle [-check] pack.json [number]")
+		os.Exit(2)
+	}
+
+	pack, err := loadPuzzlePack(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("Failed to load the puzzle pack: %v\n", err)
+		os.Exit(1)
+	}
+	if *check {
+		checkPuzzles(pack)
+		return
+	}
+
+	number := 1
+	if flags.NArg() == 2 {
+		if _, err := fmt.Sscan(flags.Arg(1), &number); err != nil || number < 1 || number > len(pack.Puzzles) {
+			fmt.Printf("Expected a puzzle number from 1 to %d\n", len(pack.Puzzles))
+			os.Exit(2)
+		}
+	}
+	puzzle := &pack.Puzzles[number-1]
+
+	renderer, bindings := profileControls(lookupProfile(*profilesPath, *profileName))
+	snakeGame := sg.SnakeGame{}
+	snakeGame.Init(puzzle.Hight, puzzle.Width, false, renderer.Display, newKeyHandler(bindings), puzzle.Options()...)
+	renderer.UseGrid(snakeGame.Grid())
+	renderer.PuzzleOver(snakeGame.Run())
+}
+
+// Check every puzzle of the pack, exits with an error if any of them can't be solved
+func checkPuzzles(pack *sg.PuzzlePack) {
+	failed := false
+	for i := range pack.Puzzles {
+		puzzle := &pack.Puzzles[i]
+		if puzzle.Solution != "" {
+			if err := puzzle.Verify(puzzle.Solution); err != nil {
+				fmt.Printf("%d %s: known solution fails: %v\n", i+1, puzzle.Name, err)
+				failed = true
+			}
+		}
+
+		solution, err := puzzle.Solve()
+		if err == nil {
+			err = puzzle.Verify(solution)
+		}
+		if err != nil {
+			fmt.Printf("%d %s: %v\n", i+1, puzzle.Name, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%d %s: solvable in %d of %d moves: %s\n", i+1, puzzle.Name, len(solution), puzzle.MaxMoves, solution)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// Paint a level in the terminal, test-play it and save it to the file
+func editLevel(args []string) {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	hight := flags.Int("hight", 15, "board hight of a new level")
+	width := flags.Int("width", 15, "board width of a new level")
+	topologyName := flags.String("topology", "walled", "border behavior of a new level, see the main flags")
+	profileName := flags.String("profile", "", "profile with the theme and the key bindings of the test plays, the last played one if empty")
+	profilesPath := flags.String("profiles", "snake.profiles", "file the player profiles are kept in")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: snake edit [-hight N] [-width N] [-topology name] level.json")
+		os.Exit(2)
+	}
+	path := flags.Arg(0)
+
+	level, err := loadLevel(path)
+	if os.IsNotExist(err) {
+		var topology sg.EdgeTopology
+		if topology, err = sg.ParseEdgeTopology(*topologyName); err == nil {
+			level = &sg.Level{Hight: *hight, Width: *width, Topology: &topology}
+			err = level.Validate()
+		}
+	}
+	if err != nil {
+		fmt.Printf("Failed to load level: %v\n", err)
+		os.Exit(1)
+	}
+	if level.Hight > sg.DefaultViewportSize || level.Width > sg.DefaultViewportSize {
+		fmt.Printf("Levels larger than %dx%d can't be edited\n", sg.DefaultViewportSize, sg.DefaultViewportSize)
+		os.Exit(1)
+	}
+
+	keysEvents, err := keyboard.GetKeys(10)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		_ = keyboard.Close()
+	}()
+
+	directions := map[keyboard.Key]sg.Direction{
+		keyboard.KeyArrowUp:    sg.DirectionUp,
+		keyboard.KeyArrowRight: sg.DirectionRight,
+		keyboard.KeyArrowDown:  sg.DirectionDown,
+		keyboard.KeyArrowLeft:  sg.DirectionLeft,
+	}
+	tools := map[rune]editor.Tool{
+		'w': editor.ToolWall,
+		'p': editor.ToolPortal,
+		's': editor.ToolSpawn,
+		'f': editor.ToolFood,
+		'e': editor.ToolExit,
+		'x': editor.ToolErase,
+	}
+
+	renderer, bindings := profileControls(lookupProfile(*profilesPath, *profileName))
+	e := editor.New(*level)
+	leaving := false
+	for {
+		renderer.Editor(e.Cells(), e.Cursor, e.Status)
+		event := <-keysEvents
+		if event.Err != nil {
+			panic(event.Err)
+		}
+
+		// Esc has to be pressed twice to drop unsaved changes
+		confirmed := leaving
+		leaving = false
+		if d, ok := directions[event.Key]; ok {
+			e.Move(d)
+			continue
+		}
+		if tool, ok := tools[event.Rune]; ok {
+			e.Paint(tool)
+			continue
+		}
+		switch {
+		case event.Key == keyboard.KeyDelete:
+			e.Paint(editor.ToolErase)
+		case event.Rune == 't':
+			testPlay(e, renderer, bindings, keysEvents)
+		case event.Key == keyboard.KeyCtrlS:
+			if level, ok := e.Finished(); ok {
+				if err := writeFile(path, level.Save); err != nil {
+					e.Status = fmt.Sprintf("Failed to save: %v", err)
+				} else {
+					e.Dirty = false
+					e.Status = "Saved to " + path
 				}
+			}
+		case event.Key == keyboard.KeyEsc:
+			if e.Dirty && !confirmed {
+				leaving = true
+				e.Status = "Unsaved changes, press Esc again to quit"
+				continue
+			}
+			return
+		}
+	}
+}
+
+// Write a generated level to the file or the standard output
+func generateLevel(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	kind := flags.String("kind", "maze", "level kind: maze (recursive backtracker), prim (Prim's maze), caves or arena (symmetric multiplayer)")
+	seed := flags.Int64("seed", 0, "generator seed, 0 picks a random one")
+	hight := flags.Int("hight", 15, "board hight")
+	width := flags.Int("width", 15, "board width")
+	players := flags.Int("players", 2, "arena spawns, 2 or 4")
+	runway := flags.Int("runway", 3, "free cells ahead of every spawn")
+	out := flags.String("out", "", "level file, the standard output by default")
+	_ = flags.Parse(args)
 
-				switch event.Key {
-				case keyboard.KeyArrowUp:
-					turn <- sg.DirectionUp
-				case keyboard.KeyArrowRight:
-					turn <- sg.DirectionRight
-				case keyboard.KeyArrowDown:
-					turn <- sg.DirectionDown
-				case keyboard.KeyArrowLeft:
-					turn <- sg.DirectionLeft
-
-				case keyboard.KeyEsc:
-					quit <- true
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	level, err := levelgen.Generate(levelgen.Settings{
+		Kind:    *kind,
+		Hight:   *hight,
+		Width:   *width,
+		Seed:    *seed,
+		Players: *players,
+		Runway:  *runway,
+	})
+	if err != nil {
+		fmt.Printf("Failed to generate the level: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *out == "" {
+		err = level.Save(os.Stdout)
+	} else {
+		err = writeFile(*out, level.Save)
+	}
+	if err != nil {
+		fmt.Printf("Failed to save the level: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// Play the edited level until the game is over, the keys go to the game meanwhile
+func testPlay(e *editor.Editor, renderer *terminal.Renderer, bindings map[rune]sg.Command, keysEvents <-chan keyboard.KeyEvent) {
+	level, ok := e.Finished()
+	if !ok {
+		return
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	forward := func(commands chan<- sg.Command) {
+		for {
+			select {
+			case event := <-keysEvents:
+				command, ok := boundCommand(bindings, event)
+				if !ok {
+					continue
+				}
+				select {
+				case commands <- command:
+				case <-done:
 					return
-				default:
 				}
+			case <-done:
+				return
 			}
 		}
 	}
-)
 
-func main() {
 	snakeGame := sg.SnakeGame{}
-	snakeGame.Init(15, 15, false, displayFunc, keyHandlerFunc)
-	score := snakeGame.Run()
+	snakeGame.Init(level.Hight, level.Width, false, renderer.Display, forward, sg.WithLevel(&level))
+	renderer.UseGrid(snakeGame.Grid())
+	result := snakeGame.Run()
+	e.Status = fmt.Sprintf("Test play over with score %d", result.Score)
+}
 
-	// Game over
-	cls.ClearScreen()
-	fmt.Printf("<< Score: %d >>\n", score)
+// Play the challenge of the day and store the result with its input log
+func playDaily(args []string) {
+	flags := flag.NewFlagSet("daily", flag.ExitOnError)
+	player := flags.String("player", os.Getenv("USER"), "player name on the scoreboard")
+	out := flags.String("out", "", "result file, daily-<date>.json by default")
+	profileName := flags.String("profile", "", "profile with the theme and key bindings, the last played one if empty")
+	profilesPath := flags.String("profiles", "snake.profiles", "file the player profiles are kept in")
+	_ = flags.Parse(args)
+
+	challenge := daily.Today()
+	if *out == "" {
+		*out = "daily-" + challenge.Date + ".json"
+	}
+
+	renderer, bindings := profileControls(lookupProfile(*profilesPath, *profileName))
+	snakeGame := sg.SnakeGame{}
+	snakeGame.Init(challenge.Hight, challenge.Width, false, renderer.Display, newKeyHandler(bindings), append(challenge.Options(), sg.WithRecording())...)
+	renderer.UseGrid(snakeGame.Grid())
+	result := snakeGame.Run()
+	renderer.GameOver(result)
+
+	claim := daily.Result{
+		Player:  *player,
+		Date:    challenge.Date,
+		Score:   result.Score,
+		Outcome: result.Outcome,
+		Log:     snakeGame.InputLog(),
+	}
+	if err := writeFile(*out, claim.Save); err != nil {
+		fmt.Printf("Failed to save the result: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Result saved to %s, submit it with: snake verify %s\n", *out, *out)
+}
+
+// Re-simulate daily results and add the confirmed ones to the scoreboard
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	scoreboardPath := flags.String("scoreboard", "daily.scores", "shared daily scoreboard file")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Println("Usage: snake verify [-scoreboard file] result.json...")
+		os.Exit(2)
+	}
+
+	scoreboard, err := loadScoreboard(*scoreboardPath)
+	if err != nil {
+		fmt.Printf("Failed to load the scoreboard: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, path := range flags.Args() {
+		claim, err := loadResult(path)
+		if err == nil {
+			err = daily.Verify(claim)
+		}
+		if err != nil {
+			fmt.Printf("%s: rejected: %v\n", path, err)
+			failed = true
+			continue
+		}
+		place := scoreboard.Add(claim)
+		fmt.Printf("%s: %s scored %d on %s, place %d\n", path, claim.Player, claim.Score, claim.Date, place)
+	}
+
+	if err := writeFile(*scoreboardPath, scoreboard.Save); err != nil {
+		fmt.Printf("Failed to save the scoreboard: %v\n", err)
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// Read a daily result file
+func loadResult(path string) (daily.Result, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return daily.Result{}, err
+	}
+	defer file.Close()
+	return daily.LoadResult(file)
+}
+
+// Read the daily scoreboard, a missing file gives an empty one
+func loadScoreboard(path string) (*daily.Scoreboard, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return daily.LoadScoreboard(strings.NewReader(""))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return daily.LoadScoreboard(file)
+}
+
+// Print every achievement with the unlock date of the profile
+func listAchievements(args []string) {
+	flags := flag.NewFlagSet("achievements", flag.ExitOnError)
+	name := flags.String("profile", "", "profile to show the achievements of, the last played one if empty")
+	flags.StringVar(name, "player", "", "same as -profile, kept for older scripts")
+	path := flags.String("profiles", "snake.profiles", "file the player profiles are kept in")
+	_ = flags.Parse(args)
+
+	profile := findProfile(*path, *name)
+	for _, a := range achievements.All {
+		status := "locked"
+		if date, ok := profile.Achievements[a.ID]; ok {
+			status = "unlocked " + date.Format(time.DateOnly)
+		}
+		fmt.Printf("%-14s %-20s %s\n", a.Title, "("+status+")", a.Description)
+	}
+}
+
+// Change the theme and key bindings of a profile, creating it if missing, and print it
+func editProfile(args []string) {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	path := flags.String("profiles", "snake.profiles", "file the player profiles are kept in")
+	theme := flags.String("theme", "", "terminal theme: classic, blocks or color")
+	var binds []string
+	flags.Func("bind", "bind a key to an action as key=action, an empty action unbinds, actions: "+strings.Join(profiles.Actions(), ", "), func(s string) error {
+		binds = append(binds, s)
+		return nil
+	})
+	_ = flags.Parse(args)
+
+	store, err := loadProfiles(*path)
+	if err != nil {
+		fmt.Printf("Failed to load the profiles: %v\n", err)
+		os.Exit(1)
+	}
+	if flags.NArg() != 1 {
+		fmt.Println("Usage: snake profile [-theme name] [-bind key=action]... name")
+		for _, p := range store.Profiles {
+			fmt.Println(p.Name)
+		}
+		os.Exit(2)
+	}
+
+	name := flags.Arg(0)
+	profile := store.Get(name)
+	created := profile == nil
+	if created {
+		if profile, err = store.Create(name); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+	if *theme != "" {
+		if _, ok := terminal.Themes[*theme]; !ok {
+			fmt.Printf("Unknown theme %q\n", *theme)
+			os.Exit(2)
+		}
+		profile.Theme = *theme
+	}
+	for _, bind := range binds {
+		key, action, _ := strings.Cut(bind, "=")
+		if err := profile.Bind(key, action); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+	if err := writeFile(*path, store.Save); err != nil {
+		fmt.Printf("Failed to save the profile: %v\n", err)
+		os.Exit(1)
+	}
+	if created {
+		fmt.Printf("Profile %s created\n", name)
+	}
+
+	stats := profile.Stats
+	fmt.Printf("Profile:  %s\n", profile.Name)
+	fmt.Printf("Theme:    %s\n", cmp.Or(profile.Theme, "classic"))
+	for _, key := range slices.Sorted(maps.Keys(profile.Keys)) {
+		fmt.Printf("Key %s:    %s\n", key, profile.Keys[key])
+	}
+	fmt.Printf("Games:    %d, %d won\n", stats.Games, stats.Wins)
+	fmt.Printf("Eaten:    %d\n", stats.Eaten)
+	fmt.Printf("Played:   %s\n", stats.PlayTime.Round(time.Second))
+	fmt.Printf("Achieved: %d of %d\n", len(profile.Achievements), len(achievements.All))
+	for _, mode := range slices.Sorted(maps.Keys(profile.Scores.Modes)) {
+		best, _ := profile.Scores.Best(mode)
+		fmt.Printf("Best %s: %d\n", mode, best.Score)
+	}
+}
+
+// Profile with the name or the last played one, exits if there is none
+func findProfile(path string, name string) *profiles.Profile {
+	profile := lookupProfile(path, name)
+	if profile == nil {
+		fmt.Println("No such profile, play a game or create it with: snake profile name")
+		os.Exit(1)
+	}
+	return profile
+}
+
+// Profile with the name or the last played one, nil if there is none
+func lookupProfile(path string, name string) *profiles.Profile {
+	store, err := loadProfiles(path)
+	if err != nil {
+		fmt.Printf("Failed to load the profiles: %v\n", err)
+		os.Exit(1)
+	}
+	return store.Get(cmp.Or(name, store.Last))
 }
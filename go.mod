@@ -1,8 +1,11 @@
 module SnakeGameGolang
 
-go 1.13
+go 1.24.0
 
 require (
 	github.com/eiannone/keyboard v0.0.0-20200508000154-caf4b762e807
-	golang.org/x/sys v0.0.0-20220422013727-9388b58f7150 // indirect
+	golang.org/x/crypto v0.48.0
+	golang.org/x/net v0.50.0
 )
+
+require golang.org/x/sys v0.41.0 // indirect
@@ -0,0 +1,170 @@
+package achievements
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"fmt"
+	"time"
+)
+
+// Ticks a toast stays in the HUD
+const toastTicks = 3 * sg.TicksPerSecond
+
+// Goal unlocked once by a player, checked on the game events
+type Achievement struct {
+	ID          string
+	Title       string
+	Description string
+	// New check per game, it keeps its own state and returns true on the unlocking event
+	check func() func(game Game, e sg.Event) bool
+}
+
+// Game being played
+type Game struct {
+	Hight int
+	Width int
+	// Game time of turn-based games only counts the moves
+	TurnBased bool
+}
+
+// Every achievement in the display order
+var All = []Achievement{
+	{
+		ID:          "length-50",
+		Title:       "Long Snake",
+		Description: "Grow to the length of 50",
+		check: func() func(Game, sg.Event) bool {
+			return func(game Game, e sg.Event) bool {
+				return e.Length >= 50
+			}
+		},
+	},
+	{
+		ID:          "clear-10x10",
+		Title:       "Clean Sweep",
+		Description: "Fill a board of at least 10x10 so there is no room left for food",
+		check: func() func(Game, sg.Event) bool {
+			return func(game Game, e sg.Event) bool {
+				return e.Kind == sg.EventCleared && game.Hight >= 10 && game.Width >= 10
+			}
+		},
+	},
+	{
+		ID:          "survive-5m",
+		Title:       "Survivor",
+		Description: "Stay alive for 5 minutes of game time in a timed game",
+		check: func() func(Game, sg.Event) bool {
+			var since time.Duration
+			return func(game Game, e sg.Event) bool {
+				if e.Kind == sg.EventCrash {
+					since = e.Elapsed
+				}
+				return !game.TurnBased && e.Kind == sg.EventTick && e.Elapsed-since >= 5*time.Minute
+			}
+		},
+	},
+	{
+		ID:          "quick-eater",
+		Title:       "Quick Eater",
+		Description: "Eat 3 food within 10 ticks",
+		check: func() func(Game, sg.Event) bool {
+			var eaten []int
+			return func(game Game, e sg.Event) bool {
+				if e.Kind != sg.EventEat {
+					return false
+				}
+				eaten = append(eaten, e.Tick)
+				if len(eaten) > 3 {
+					eaten = eaten[1:]
+				}
+				return len(eaten) == 3 && e.Tick-eaten[0] <= 10
+			}
+		},
+	},
+	{
+		ID:          "no-left-turns",
+		Title:       "Right-Handed",
+		Description: "Win a game by eating and turning right only",
+		check: func() func(Game, sg.Event) bool {
+			turnedLeft, turned, ate := false, false, false
+			return func(game Game, e sg.Event) bool {
+				turnedLeft = turnedLeft || e.TurnsLeft()
+				turned = turned || e.Kind == sg.EventTurn
+				ate = ate || e.Kind == sg.EventEat
+				won := e.Kind == sg.EventCleared || e.Kind == sg.EventAllEaten || e.Kind == sg.EventExit
+				return won && turned && ate && !turnedLeft
+			}
+		},
+	},
+}
+
+// Unlock dates of a player's achievements
+type Unlocks map[string]time.Time
+
+// Record the achievement, false if it was unlocked before
+func (u Unlocks) Unlock(id string, date time.Time) bool {
+	if _, ok := u[id]; ok {
+		return false
+	}
+	u[id] = date
+	return true
+}
+
+// Checks the events of a game against the locked achievements of a player,
+// the handler and the toasts are used from the game loop only
+type Tracker struct {
+	unlocks Unlocks
+	game    Game
+	checks  map[string]func(Game, sg.Event) bool
+	tick    int
+	toasts  []toast
+	// Achievements unlocked in this game
+	Unlocked []Achievement
+}
+
+// Notification shown until the tick
+type toast struct {
+	text  string
+	until int
+}
+
+// Start tracking a game of the player, new unlocks are added to the player's ones
+func NewTracker(unlocks Unlocks) *Tracker {
+	t := &Tracker{unlocks: unlocks, checks: make(map[string]func(Game, sg.Event) bool)}
+	for _, a := range All {
+		if _, ok := unlocks[a.ID]; !ok {
+			t.checks[a.ID] = a.check()
+		}
+	}
+	return t
+}
+
+// Describe the game once it is initialized or resumed
+func (t *Tracker) SetGame(game Game) {
+	t.game = game
+}
+
+// Check the event and unlock the achievements it completes, matches sg.EventHandler
+func (t *Tracker) Handle(e sg.Event) {
+	t.tick = e.Tick
+	for _, a := range All {
+		check, ok := t.checks[a.ID]
+		if !ok || !check(t.game, e) {
+			continue
+		}
+		delete(t.checks, a.ID)
+		t.unlocks.Unlock(a.ID, time.Now())
+		t.Unlocked = append(t.Unlocked, a)
+		t.toasts = append(t.toasts, toast{text: fmt.Sprintf("Achievement unlocked: %s", a.Title), until: e.Tick + toastTicks})
+	}
+}
+
+// Notifications to show in the HUD now
+func (t *Tracker) Toasts() []string {
+	var texts []string
+	for _, toast := range t.toasts {
+		if toast.until > t.tick {
+			texts = append(texts, toast.text)
+		}
+	}
+	return texts
+}
@@ -1,32 +1,43 @@
 package clearscreen
 
 import (
+	"io"
 	"os"
 	"os/exec"
 	"runtime"
 )
 
-var clearScreen map[string]func()
+var clearScreen map[string]func(w io.Writer)
 
 func init() {
-	clearScreen = make(map[string]func())
-	clearScreen["linux"] = func() {
+	clearScreen = make(map[string]func(w io.Writer))
+	clearScreen["linux"] = func(w io.Writer) {
 		cmd := exec.Command("clear")
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = w
 		cmd.Run()
 	}
-	clearScreen["windows"] = func() {
+	clearScreen["windows"] = func(w io.Writer) {
 		cmd := exec.Command("cmd", "/c", "cls")
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = w
 		cmd.Run()
 	}
 }
 
 func ClearScreen() {
+	ClearScreenTo(os.Stdout)
+}
+
+// Clear the screen using the OS command with output redirected to w
+func ClearScreenTo(w io.Writer) {
 	value, ok := clearScreen[runtime.GOOS]
 	if ok {
-		value()
+		value(w)
 	} else {
 		panic("Unknow OS")
 	}
 }
+
+// Clear a remote terminal with ANSI escape codes
+func ClearANSI(w io.Writer) {
+	io.WriteString(w, "\033[H\033[2J")
+}
@@ -0,0 +1,203 @@
+package daily
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// Date format of the challenges
+const DateLayout = "2006-01-02"
+
+// Seed and rules shared by everyone playing on the date
+type Challenge struct {
+	Date     string `json:"date"`
+	Seed     int64  `json:"seed"`
+	Hight    int    `json:"hight"`
+	Width    int    `json:"width"`
+	Topology string `json:"topology"`
+	Food     string `json:"food"`
+	Grow     int    `json:"grow"`
+	Portals  int    `json:"portals"`
+	Balls    int    `json:"balls"`
+	// Game time, dying costs a fixed penalty of it
+	Minutes int `json:"minutes"`
+}
+
+// Game time lost on every death
+const deathPenalty = 15 * time.Second
+
+var (
+	topologies = []string{"torus", "walled", "bounce", "klein"}
+	foods      = []string{"uniform", "distance", "edges"}
+)
+
+// Challenge of the current UTC date
+func Today() Challenge {
+	challenge, _ := ForDate(time.Now().UTC().Format(DateLayout))
+	return challenge
+}
+
+// Derive the challenge from the date in the DateLayout format
+func ForDate(date string) (Challenge, error) {
+	if _, err := time.Parse(DateLayout, date); err != nil {
+		return Challenge{}, fmt.Errorf("invalid challenge date %q", date)
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte("snake-daily/" + date))
+	seed := hash.Sum64()
+	r := rand.New(rand.NewPCG(seed, seed>>32))
+
+	return Challenge{
+		Date:     date,
+		Seed:     int64(seed),
+		Hight:    10 + r.IntN(11),
+		Width:    10 + r.IntN(11),
+		Topology: topologies[r.IntN(len(topologies))],
+		Food:     foods[r.IntN(len(foods))],
+		Grow:     1 + r.IntN(3),
+		Portals:  r.IntN(3),
+		Balls:    r.IntN(2),
+		Minutes:  2 + r.IntN(3),
+	}, nil
+}
+
+// Game time of the challenge
+func (c Challenge) Duration() time.Duration {
+	return time.Duration(c.Minutes) * time.Minute
+}
+
+// Most main loop steps a game of the challenge can take, the time runs out after them
+func (c Challenge) MaxSteps() int {
+	return int(c.Duration()/(time.Second/sg.TicksPerSecond)) + 1
+}
+
+// Engine options playing the challenge
+func (c Challenge) Options() []sg.Option {
+	topology, err := sg.ParseEdgeTopology(c.Topology)
+	if err != nil {
+		panic(err)
+	}
+	spawner, err := sg.FoodConfig{Strategy: c.Food, MinDistance: 5}.Spawner()
+	if err != nil {
+		panic(err)
+	}
+	return []sg.Option{
+		sg.WithSeed(c.Seed),
+		sg.WithMode(sg.TimeAttack{Duration: c.Duration(), Penalty: deathPenalty}),
+		sg.WithTopology(topology),
+		sg.WithFoodSpawner(spawner),
+		sg.WithRules(sg.GrowOnEat{Segments: c.Grow}, sg.ScoreOnEat{Points: 1}),
+		sg.WithRandomPortals(c.Portals),
+		sg.WithRandomEnemies(c.Balls, 0),
+	}
+}
+
+// Claimed challenge result with the input log proving it
+type Result struct {
+	Player  string      `json:"player"`
+	Date    string      `json:"date"`
+	Score   int         `json:"score"`
+	Outcome sg.Outcome  `json:"outcome"`
+	Log     sg.InputLog `json:"log"`
+}
+
+// Read a result
+func LoadResult(r io.Reader) (Result, error) {
+	var result Result
+	err := json.NewDecoder(r).Decode(&result)
+	return result, err
+}
+
+// Write the result
+func (result Result) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(result)
+}
+
+// Re-simulate the input log and check that it gives the claimed result
+func Verify(result Result) error {
+	challenge, err := ForDate(result.Date)
+	if err != nil {
+		return err
+	}
+
+	if result.Log.Steps > challenge.MaxSteps() {
+		return fmt.Errorf("log of %d steps is longer than the challenge's %d", result.Log.Steps, challenge.MaxSteps())
+	}
+
+	game := sg.SnakeGame{}
+	game.Init(challenge.Hight, challenge.Width, false,
+		func(board [][]sg.Cell, score int, hud sg.HUD) {},
+		func(commands chan<- sg.Command) {},
+		challenge.Options()...)
+	replayed := game.Replay(result.Log)
+	if replayed.Outcome != result.Outcome {
+		return fmt.Errorf("claimed outcome %v doesn't match the replayed %v", result.Outcome, replayed.Outcome)
+	}
+	if replayed.Score != result.Score {
+		return fmt.Errorf("claimed score %d doesn't match the replayed %d", result.Score, replayed.Score)
+	}
+	return nil
+}
+
+// Verified score of a player
+type Entry struct {
+	Player string `json:"player"`
+	Score  int    `json:"score"`
+}
+
+// Best verified scores per challenge date
+type Scoreboard struct {
+	Days map[string][]Entry `json:"days"`
+}
+
+// Read the scoreboard, an empty input gives an empty scoreboard
+func LoadScoreboard(r io.Reader) (*Scoreboard, error) {
+	board := &Scoreboard{}
+	if err := json.NewDecoder(r).Decode(board); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if board.Days == nil {
+		board.Days = make(map[string][]Entry)
+	}
+	return board, nil
+}
+
+// Write the scoreboard
+func (s *Scoreboard) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(s)
+}
+
+// Add the verified result keeping the best score per player, returns the player's 1-based place
+func (s *Scoreboard) Add(result Result) int {
+	entries := s.Days[result.Date]
+	found := false
+	for i := range entries {
+		if entries[i].Player == result.Player {
+			entries[i].Score = max(entries[i].Score, result.Score)
+			found = true
+		}
+	}
+	if !found {
+		entries = append(entries, Entry{Player: result.Player, Score: result.Score})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+	s.Days[result.Date] = entries
+
+	for i, entry := range entries {
+		if entry.Player == result.Player {
+			return i + 1
+		}
+	}
+	return 0
+}
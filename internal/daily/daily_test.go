@@ -0,0 +1,60 @@
+package daily
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"testing"
+)
+
+// Result of replaying the log on the challenge of the date
+func replay(t *testing.T, date string, log sg.InputLog) Result {
+	challenge, err := ForDate(date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := sg.SnakeGame{}
+	game.Init(challenge.Hight, challenge.Width, false, func([][]sg.Cell, int, sg.HUD) {}, func(chan<- sg.Command) {}, challenge.Options()...)
+	result := game.Replay(log)
+	return Result{Date: date, Score: result.Score, Outcome: result.Outcome, Log: log}
+}
+
+// Zigzag of turns every few steps, quitting after the steps
+func zigzag(steps int, every int) sg.InputLog {
+	var log sg.InputLog
+	turns := []sg.Direction{sg.DirectionRight, sg.DirectionDown, sg.DirectionLeft, sg.DirectionDown}
+	for step := every; step < steps; step += every {
+		log.Commands = append(log.Commands, sg.LoggedCommand{Step: step, Kind: sg.CommandTurn, Direction: turns[step/every%len(turns)]})
+	}
+	log.Commands = append(log.Commands, sg.LoggedCommand{Step: steps, Kind: sg.CommandQuit})
+	log.Steps = steps
+	return log
+}
+
+func TestVerify(t *testing.T) {
+	honest := replay(t, "2025-03-15", zigzag(300, 3))
+	if honest.Score == 0 {
+		t.Fatal("expected the log to score")
+	}
+	challenge, _ := ForDate("2025-03-15")
+	long := zigzag(challenge.MaxSteps()+10, 7)
+
+	tests := []struct {
+		name   string
+		result func() Result
+		ok     bool
+	}{
+		{"honest", func() Result { return honest }, true},
+		{"other date", func() Result { return replay(t, "2025-03-17", zigzag(300, 4)) }, true},
+		{"raised score", func() Result { r := honest; r.Score++; return r }, false},
+		{"other outcome", func() Result { r := honest; r.Outcome = sg.OutcomeWon; return r }, false},
+		{"wrong date", func() Result { r := honest; r.Date = "2025-03-14"; return r }, false},
+		{"longer than the challenge", func() Result { return Result{Date: "2025-03-15", Outcome: sg.OutcomeQuit, Log: long} }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Verify(test.result())
+			if (err == nil) != test.ok {
+				t.Errorf("Verify() = %v, want ok %v", err, test.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package editor
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"fmt"
+	"slices"
+)
+
+// Marker painted at the cursor
+type Tool int8
+
+const (
+	ToolWall Tool = iota
+	ToolPortal
+	// Single-segment snake start, the first spawn of multiplayer levels
+	ToolSpawn
+	// Scripted food eaten in the order of painting, the level falls back
+	// to the uniform food once the last one is removed
+	ToolFood
+	ToolExit
+	ToolErase
+)
+
+// Level being edited with the cursor position
+type Editor struct {
+	Level  sg.Level
+	Cursor sg.Point
+	// Outcome of the last action
+	Status string
+	// Unsaved changes
+	Dirty bool
+
+	// First cell of a portal pair waiting for the second one
+	portal *sg.Point
+}
+
+// Start editing the level with the cursor in the center
+func New(level sg.Level) *Editor {
+	return &Editor{
+		Level:  level,
+		Cursor: sg.Point{X: level.Width / 2, Y: level.Hight / 2},
+	}
+}
+
+// Move the cursor a cell in the direction, it stops at the board edges
+func (e *Editor) Move(d sg.Direction) {
+	next := sg.SquareGrid{}.Neighbor(e.Cursor, d)
+	e.Cursor = sg.Point{
+		X: max(0, min(next.X, e.Level.Width-1)),
+		Y: max(0, min(next.Y, e.Level.Hight-1)),
+	}
+}
+
+// Apply the tool at the cursor, painting a marker on its own cell removes it
+func (e *Editor) Paint(tool Tool) {
+	p := e.Cursor
+	if tool != ToolSpawn && tool != ToolErase && slices.Contains(e.snake(), p) {
+		e.Status = "The spawn point is in the way"
+		return
+	}
+
+	switch tool {
+	case ToolWall:
+		if i := slices.Index(e.Level.Walls, p); i >= 0 {
+			e.Level.Walls = slices.Delete(e.Level.Walls, i, i+1)
+			e.Status = "Wall removed"
+			break
+		}
+		e.clear(p)
+		e.Level.Walls = append(e.Level.Walls, p)
+		e.Status = "Wall placed"
+
+	case ToolPortal:
+		switch {
+		case e.portal == nil:
+			e.clear(p)
+			e.portal = &p
+			e.Status = "Portal placed, paint its pair"
+		case *e.portal == p:
+			e.portal = nil
+			e.Status = "Portal removed"
+		default:
+			e.clear(p)
+			e.Level.Portals = append(e.Level.Portals, sg.PortalPair{A: *e.portal, B: p})
+			e.portal = nil
+			e.Status = "Portal pair linked"
+		}
+
+	case ToolSpawn:
+		e.clear(p)
+		e.Level.Snake = []sg.Point{p}
+		if len(e.Level.Spawns) > 0 {
+			e.Level.Spawns[0].At = p
+		}
+		e.Status = "Spawn point moved"
+
+	case ToolFood:
+		if i := slices.Index(e.Level.Food.Points, p); i >= 0 {
+			e.Level.Food.Points = slices.Delete(e.Level.Food.Points, i, i+1)
+			if len(e.Level.Food.Points) == 0 {
+				e.Level.Food = sg.FoodConfig{}
+			}
+			e.Status = "Food removed"
+			break
+		}
+		e.clear(p)
+		e.Level.Food.Strategy = "scripted"
+		e.Level.Food.Points = append(e.Level.Food.Points, p)
+		e.Status = fmt.Sprintf("Food %d placed", len(e.Level.Food.Points))
+
+	case ToolExit:
+		if e.Level.Exit != nil && *e.Level.Exit == p {
+			e.Level.Exit = nil
+			e.Status = "Exit removed"
+			break
+		}
+		e.clear(p)
+		e.Level.Exit = &p
+		e.Status = "Exit moved"
+
+	case ToolErase:
+		e.clear(p)
+		e.Status = "Cell erased"
+	}
+	e.Dirty = true
+}
+
+// Every marker drawn on the board, the cursor is not included
+func (e *Editor) Cells() [][]sg.Cell {
+	cells := make([][]sg.Cell, e.Level.Hight)
+	for i := range cells {
+		cells[i] = make([]sg.Cell, e.Level.Width)
+	}
+	set := func(p sg.Point, cell sg.Cell) {
+		cells[p.Y][p.X] = cell
+	}
+
+	for _, p := range e.Level.Walls {
+		set(p, sg.CellWall)
+	}
+	for _, pair := range e.Level.Portals {
+		set(pair.A, sg.CellPortal)
+		set(pair.B, sg.CellPortal)
+	}
+	if e.portal != nil {
+		set(*e.portal, sg.CellPortal)
+	}
+	for _, p := range e.Level.Food.Points {
+		set(p, sg.CellFood)
+	}
+	if e.Level.Exit != nil {
+		set(*e.Level.Exit, sg.CellExit)
+	}
+	// Entities are shown over every cell they may move along
+	for _, config := range e.Level.Entities {
+		cell := sg.CellObstacle
+		if entity, err := config.Entity(); err == nil {
+			cell = entity.Look()
+		}
+		for _, p := range config.Points() {
+			set(p, cell)
+		}
+	}
+	// The snake covers the first spawn
+	for _, spawn := range e.Level.Spawns {
+		set(spawn.At, sg.CellRivalHead)
+	}
+	for i, p := range e.snake() {
+		if i == 0 {
+			set(p, sg.CellSnakeHead)
+		} else {
+			set(p, sg.CellSnakeTail)
+		}
+	}
+	return cells
+}
+
+// Copy of the level to play or save, false with the reason in the status if it's broken
+func (e *Editor) Finished() (sg.Level, bool) {
+	if e.portal != nil {
+		e.Status = "Paint the pair of the last portal first"
+		return sg.Level{}, false
+	}
+	level := e.Level
+	level.Walls = slices.Clone(level.Walls)
+	level.Portals = slices.Clone(level.Portals)
+	level.Snake = slices.Clone(level.Snake)
+	level.Spawns = slices.Clone(level.Spawns)
+	level.Food.Points = slices.Clone(level.Food.Points)
+	level.Entities = slices.Clone(level.Entities)
+	if err := level.Validate(); err != nil {
+		e.Status = fmt.Sprintf("Level is invalid: %v", err)
+		return sg.Level{}, false
+	}
+	return level, true
+}
+
+// Snake start of the level, the engine puts it on the first spawn or in the center by default
+func (e *Editor) snake() []sg.Point {
+	if len(e.Level.Snake) > 0 {
+		return e.Level.Snake
+	}
+	if len(e.Level.Spawns) > 0 {
+		return []sg.Point{e.Level.Spawns[0].At}
+	}
+	return []sg.Point{{X: e.Level.Width / 2, Y: e.Level.Hight / 2}}
+}
+
+// Remove every marker from the cell except for the snake and the first spawn under it,
+// entities moving over it go whole
+func (e *Editor) clear(p sg.Point) {
+	e.Level.Walls = slices.DeleteFunc(e.Level.Walls, func(wall sg.Point) bool {
+		return wall == p
+	})
+	e.Level.Portals = slices.DeleteFunc(e.Level.Portals, func(pair sg.PortalPair) bool {
+		return pair.A == p || pair.B == p
+	})
+	if e.portal != nil && *e.portal == p {
+		e.portal = nil
+	}
+	if i := slices.Index(e.Level.Food.Points, p); i >= 0 {
+		e.Level.Food.Points = slices.Delete(e.Level.Food.Points, i, i+1)
+		if len(e.Level.Food.Points) == 0 {
+			e.Level.Food = sg.FoodConfig{}
+		}
+	}
+	if e.Level.Exit != nil && *e.Level.Exit == p {
+		e.Level.Exit = nil
+	}
+	e.Level.Entities = slices.DeleteFunc(e.Level.Entities, func(config sg.EntityConfig) bool {
+		return slices.Contains(config.Points(), p)
+	})
+	for i := len(e.Level.Spawns) - 1; i > 0; i-- {
+		if e.Level.Spawns[i].At == p {
+			e.Level.Spawns = slices.Delete(e.Level.Spawns, i, i+1)
+		}
+	}
+}
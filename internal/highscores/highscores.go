@@ -0,0 +1,66 @@
+package highscores
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// Number of entries kept per mode
+const Size = 10
+
+// Single ranked result
+type Entry struct {
+	Score int       `json:"score"`
+	Date  time.Time `json:"date"`
+}
+
+// Best results ranked separately per game mode
+type Table struct {
+	Modes map[string][]Entry `json:"modes"`
+}
+
+// Read the table, an empty input gives an empty table
+func Load(r io.Reader) (*Table, error) {
+	table := &Table{}
+	if err := json.NewDecoder(r).Decode(table); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if table.Modes == nil {
+		table.Modes = make(map[string][]Entry)
+	}
+	return table, nil
+}
+
+// Write the table
+func (t *Table) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(t)
+}
+
+// Rank the entry within its mode, returns its 1-based place or 0 if it didn't make the table
+func (t *Table) Add(mode string, entry Entry) int {
+	entries := t.Modes[mode]
+	place := sort.Search(len(entries), func(i int) bool {
+		return entries[i].Score < entry.Score
+	})
+	if place >= Size {
+		return 0
+	}
+
+	entries = append(entries, Entry{})
+	copy(entries[place+1:], entries[place:])
+	entries[place] = entry
+	t.Modes[mode] = entries[:min(len(entries), Size)]
+	return place + 1
+}
+
+// Best entry of the mode, false if nothing is ranked yet
+func (t *Table) Best(mode string) (Entry, bool) {
+	if len(t.Modes[mode]) == 0 {
+		return Entry{}, false
+	}
+	return t.Modes[mode][0], true
+}
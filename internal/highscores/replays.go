@@ -0,0 +1,52 @@
+package highscores
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Best recorded run per game setup, e.g. to race against it as a ghost
+type Replays struct {
+	Runs map[string]Replay `json:"runs"`
+}
+
+// Recorded run with its result
+type Replay struct {
+	Score int         `json:"score"`
+	Date  time.Time   `json:"date"`
+	Log   sg.InputLog `json:"log"`
+}
+
+// Read the replays, an empty input gives no replays
+func LoadReplays(r io.Reader) (*Replays, error) {
+	replays := &Replays{}
+	if err := json.NewDecoder(r).Decode(replays); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if replays.Runs == nil {
+		replays.Runs = make(map[string]Replay)
+	}
+	return replays, nil
+}
+
+// Write the replays
+func (r *Replays) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// Keep the run if it beats the best one of the setup, true if it does
+func (r *Replays) Add(setup string, replay Replay) bool {
+	if best, ok := r.Runs[setup]; ok && best.Score >= replay.Score {
+		return false
+	}
+	r.Runs[setup] = replay
+	return true
+}
+
+// Best run of the setup, false if nothing is recorded yet
+func (r *Replays) Best(setup string) (Replay, bool) {
+	replay, ok := r.Runs[setup]
+	return replay, ok
+}
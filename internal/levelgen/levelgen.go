@@ -0,0 +1,423 @@
+package levelgen
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Level generation settings, the same settings always give the same level
+type Settings struct {
+	// maze, prim, caves or arena
+	Kind  string
+	Hight int
+	Width int
+	Seed  int64
+	// Symmetric spawns of the arena, 2 or 4
+	Players int
+	// Free cells straight ahead of every spawn
+	Runway int
+}
+
+// Attempts to grow caves or arena obstacles before falling back to an open board
+const attempts = 10
+
+// Wall map of the board being generated
+type plan struct {
+	hight, width int
+	walls        [][]bool
+}
+
+// Generate the level, every free cell is reachable from the spawn
+func Generate(s Settings) (*sg.Level, error) {
+	if s.Hight < 5 || s.Width < 5 || s.Hight > sg.MaxBoardSize || s.Width > sg.MaxBoardSize {
+		return nil, fmt.Errorf("generated levels need a board of at least 5x5")
+	}
+	if s.Runway < 0 || s.Runway > min(s.Hight, s.Width)/2-1 {
+		return nil, fmt.Errorf("runway of %d doesn't fit the board", s.Runway)
+	}
+	r := rand.New(rand.NewPCG(uint64(s.Seed), uint64(s.Seed)>>32))
+
+	var p *plan
+	var spawns []sg.Spawn
+	switch s.Kind {
+	case "maze", "prim":
+		p = newPlan(s.Hight, s.Width, true)
+		if s.Kind == "maze" {
+			p.backtracker(r)
+		} else {
+			p.prim(r)
+		}
+		spawns = []sg.Spawn{p.spawn(r, s.Runway)}
+	case "caves":
+		p = caves(r, s.Hight, s.Width)
+		spawns = []sg.Spawn{p.spawn(r, s.Runway)}
+	case "arena":
+		if s.Players != 2 && s.Players != 4 {
+			return nil, fmt.Errorf("symmetric arenas are made for 2 or 4 players")
+		}
+		var err error
+		if p, spawns, err = arena(r, s.Hight, s.Width, s.Players, s.Runway); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown level kind %q", s.Kind)
+	}
+
+	topology := sg.Walled
+	level := &sg.Level{
+		Name:      fmt.Sprintf("%s-%d", s.Kind, s.Seed),
+		Hight:     s.Hight,
+		Width:     s.Width,
+		Topology:  &topology,
+		Snake:     []sg.Point{spawns[0].At},
+		Direction: spawns[0].Direction,
+	}
+	if len(spawns) > 1 {
+		level.Spawns = spawns
+	}
+	for y := range p.walls {
+		for x, wall := range p.walls[y] {
+			if wall {
+				level.Walls = append(level.Walls, sg.Point{X: x, Y: y})
+			}
+		}
+	}
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	return level, nil
+}
+
+// Empty board or one full of walls
+func newPlan(hight int, width int, full bool) *plan {
+	p := &plan{hight: hight, width: width, walls: make([][]bool, hight)}
+	for y := range p.walls {
+		p.walls[y] = make([]bool, width)
+		for x := range p.walls[y] {
+			p.walls[y][x] = full
+		}
+	}
+	return p
+}
+
+// Check if the point lies on the board
+func (p *plan) contains(q sg.Point) bool {
+	return q.X >= 0 && q.X < p.width && q.Y >= 0 && q.Y < p.hight
+}
+
+// Check if the point is a free board cell
+func (p *plan) free(q sg.Point) bool {
+	return p.contains(q) && !p.walls[q.Y][q.X]
+}
+
+// Walls along the board edges
+func (p *plan) border() {
+	for y := 0; y < p.hight; y++ {
+		p.walls[y][0], p.walls[y][p.width-1] = true, true
+	}
+	for x := 0; x < p.width; x++ {
+		p.walls[0][x], p.walls[p.hight-1][x] = true, true
+	}
+}
+
+// Neighbor cell in the direction
+func step(q sg.Point, d sg.Direction) sg.Point {
+	return sg.SquareGrid{}.Neighbor(q, d)
+}
+
+// Maze rooms lie on odd coordinates, the walls between them are carved out
+func (p *plan) rooms() (int, int) {
+	return (p.hight - 1) / 2, (p.width - 1) / 2
+}
+
+// Board cell of the maze room
+func room(x int, y int) sg.Point {
+	return sg.Point{X: 2*x + 1, Y: 2*y + 1}
+}
+
+// Perfect maze by the recursive backtracker, iterative to survive large boards
+func (p *plan) backtracker(r *rand.Rand) {
+	hight, width := p.rooms()
+	visited := make([]bool, hight*width)
+	start := sg.Point{X: r.IntN(width), Y: r.IntN(hight)}
+	visited[start.Y*width+start.X] = true
+	p.carve(room(start.X, start.Y))
+
+	stack := []sg.Point{start}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		var next []sg.Direction
+		for d := sg.DirectionUp; d <= sg.DirectionLeft; d++ {
+			n := step(current, d)
+			if n.X >= 0 && n.X < width && n.Y >= 0 && n.Y < hight && !visited[n.Y*width+n.X] {
+				next = append(next, d)
+			}
+		}
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		d := next[r.IntN(len(next))]
+		n := step(current, d)
+		visited[n.Y*width+n.X] = true
+		p.carve(step(room(current.X, current.Y), d))
+		p.carve(room(n.X, n.Y))
+		stack = append(stack, n)
+	}
+}
+
+// Perfect maze by the randomized Prim's algorithm
+func (p *plan) prim(r *rand.Rand) {
+	hight, width := p.rooms()
+	visited := make([]bool, hight*width)
+	type passage struct {
+		from sg.Point
+		d    sg.Direction
+	}
+	var frontier []passage
+	visit := func(q sg.Point) {
+		visited[q.Y*width+q.X] = true
+		p.carve(room(q.X, q.Y))
+		for d := sg.DirectionUp; d <= sg.DirectionLeft; d++ {
+			frontier = append(frontier, passage{from: q, d: d})
+		}
+	}
+	visit(sg.Point{X: r.IntN(width), Y: r.IntN(hight)})
+
+	for len(frontier) > 0 {
+		i := r.IntN(len(frontier))
+		next := frontier[i]
+		frontier[i] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		n := step(next.from, next.d)
+		if n.X < 0 || n.X >= width || n.Y < 0 || n.Y >= hight || visited[n.Y*width+n.X] {
+			continue
+		}
+		p.carve(step(room(next.from.X, next.from.Y), next.d))
+		visit(n)
+	}
+}
+
+// Make the cell free
+func (p *plan) carve(q sg.Point) {
+	p.walls[q.Y][q.X] = false
+}
+
+// Cellular automaton caves, only the largest open area is kept
+func caves(r *rand.Rand, hight int, width int) *plan {
+	for i := 0; i < attempts; i++ {
+		p := newPlan(hight, width, false)
+		for y := range p.walls {
+			for x := range p.walls[y] {
+				p.walls[y][x] = r.IntN(100) < 45
+			}
+		}
+		p.border()
+
+		// A cell turns into a wall with at least 5 walls around, out of the board counts as walls
+		for round := 0; round < 5; round++ {
+			next := newPlan(hight, width, false)
+			for y := range p.walls {
+				for x := range p.walls[y] {
+					next.walls[y][x] = p.wallsAround(sg.Point{X: x, Y: y}) >= 5
+				}
+			}
+			p = next
+			p.border()
+		}
+
+		if p.keepLargestArea()*3 >= (hight-2)*(width-2) {
+			return p
+		}
+	}
+
+	p := newPlan(hight, width, false)
+	p.border()
+	return p
+}
+
+// Number of walls among the 8 surrounding cells
+func (p *plan) wallsAround(q sg.Point) int {
+	walls := 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			n := sg.Point{X: q.X + dx, Y: q.Y + dy}
+			if n != q && !p.free(n) {
+				walls++
+			}
+		}
+	}
+	return walls
+}
+
+// Wall off everything but the largest connected free area, returns its size
+func (p *plan) keepLargestArea() int {
+	area := make([][]int, p.hight)
+	for y := range area {
+		area[y] = make([]int, p.width)
+	}
+	largest, largestSize, areas := 0, 0, 0
+	for y := range p.walls {
+		for x := range p.walls[y] {
+			q := sg.Point{X: x, Y: y}
+			if !p.free(q) || area[y][x] != 0 {
+				continue
+			}
+			areas++
+			size := len(p.reachable(q, func(n sg.Point) { area[n.Y][n.X] = areas }))
+			if size > largestSize {
+				largest, largestSize = areas, size
+			}
+		}
+	}
+
+	for y := range p.walls {
+		for x := range p.walls[y] {
+			if area[y][x] != largest {
+				p.walls[y][x] = true
+			}
+		}
+	}
+	return largestSize
+}
+
+// Free cells connected to the start, visit is called for each of them
+func (p *plan) reachable(start sg.Point, visit func(q sg.Point)) []sg.Point {
+	seen := map[sg.Point]bool{start: true}
+	queue := []sg.Point{start}
+	for i := 0; i < len(queue); i++ {
+		visit(queue[i])
+		for d := sg.DirectionUp; d <= sg.DirectionLeft; d++ {
+			n := step(queue[i], d)
+			if p.free(n) && !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return queue
+}
+
+// Free cells in the direction before the first wall, up to the limit
+func (p *plan) runway(q sg.Point, d sg.Direction, limit int) int {
+	n := 0
+	for q = step(q, d); n < limit && p.free(q); q = step(q, d) {
+		n++
+	}
+	return n
+}
+
+// Pick a random free cell and direction with the runway ahead,
+// the runway is carved out of the walls if no cell has one
+func (p *plan) spawn(r *rand.Rand, runway int) sg.Spawn {
+	var candidates []sg.Point
+	var directions []sg.Direction
+	for y := range p.walls {
+		for x := range p.walls[y] {
+			q := sg.Point{X: x, Y: y}
+			if !p.free(q) {
+				continue
+			}
+			for d := sg.DirectionUp; d <= sg.DirectionLeft; d++ {
+				if p.runway(q, d, runway) == runway {
+					candidates = append(candidates, q)
+					directions = append(directions, d)
+				}
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		i := r.IntN(len(candidates))
+		return sg.Spawn{At: candidates[i], Direction: directions[i]}
+	}
+
+	// Carving from a free cell keeps the board connected, the runway stays
+	// off the board edges when there is room for it
+	var free []sg.Point
+	for y := range p.walls {
+		for x := range p.walls[y] {
+			if q := (sg.Point{X: x, Y: y}); p.free(q) && y-runway >= 1 {
+				free = append(free, q)
+			}
+		}
+	}
+	spawn := sg.Point{X: p.width / 2, Y: p.hight - 2}
+	if len(free) > 0 {
+		spawn = free[r.IntN(len(free))]
+	}
+	p.carve(spawn)
+	for i, q := 0, spawn; i < runway; i++ {
+		q = step(q, sg.DirectionUp)
+		p.carve(q)
+	}
+	return sg.Spawn{At: spawn, Direction: sg.DirectionUp}
+}
+
+// Symmetric arena: random obstacles mirrored for every player, the spawns
+// face away from the nearest edge with their runways kept free
+func arena(r *rand.Rand, hight int, width int, players int, runway int) (*plan, []sg.Spawn, error) {
+	mirror := func(q sg.Point) []sg.Point {
+		if players == 2 {
+			return []sg.Point{q, {X: width - 1 - q.X, Y: hight - 1 - q.Y}}
+		}
+		return []sg.Point{q, {X: width - 1 - q.X, Y: q.Y}, {X: width - 1 - q.X, Y: hight - 1 - q.Y}, {X: q.X, Y: hight - 1 - q.Y}}
+	}
+
+	// The first spawn lies in the lower left quarter heading up, the runway stays inside of it.
+	// The second half of the mirrors is upside down and heads down
+	first := sg.Point{X: max(1, width/4), Y: max(runway+1, hight-1-hight/4)}
+	// Four players head toward the upside down mirror in the same column, their runways must not meet
+	if players == 4 && 2*(first.Y-runway) <= hight-1 {
+		return nil, nil, fmt.Errorf("arena of hight %d is too low for 4 runways of %d", hight, runway)
+	}
+	var spawns []sg.Spawn
+	for i, q := range mirror(first) {
+		direction := sg.DirectionUp
+		if i >= players/2 {
+			direction = sg.DirectionDown
+		}
+		spawns = append(spawns, sg.Spawn{At: q, Direction: direction})
+	}
+	reserved := make(map[sg.Point]bool)
+	for i, q := 0, first; i <= runway; i, q = i+1, step(q, sg.DirectionUp) {
+		for _, m := range mirror(q) {
+			reserved[m] = true
+		}
+	}
+
+	for i := 0; i < attempts; i++ {
+		p := newPlan(hight, width, false)
+		p.border()
+		for blocks := hight * width / 20; blocks > 0; blocks-- {
+			q := sg.Point{X: 1 + r.IntN(width-2), Y: 1 + r.IntN(hight-2)}
+			for _, m := range mirror(q) {
+				if !reserved[m] {
+					p.walls[m.Y][m.X] = true
+				}
+			}
+		}
+		if p.connected(first) {
+			return p, spawns, nil
+		}
+	}
+
+	p := newPlan(hight, width, false)
+	p.border()
+	return p, spawns, nil
+}
+
+// Check if every free cell is reachable from the point
+func (p *plan) connected(start sg.Point) bool {
+	free := 0
+	for y := range p.walls {
+		for x := range p.walls[y] {
+			if !p.walls[y][x] {
+				free++
+			}
+		}
+	}
+	return len(p.reachable(start, func(sg.Point) {})) == free
+}
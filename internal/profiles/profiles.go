@@ -0,0 +1,217 @@
+package profiles
+
+import (
+	"SnakeGameGolang/internal/achievements"
+	"SnakeGameGolang/internal/highscores"
+	sg "SnakeGameGolang/internal/snakegame"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// Longest profile name
+const maxNameLength = 20
+
+// Player settings and progress kept on a shared machine
+type Profile struct {
+	Name string `json:"name"`
+	// Extra key bindings from a key character to an action, e.g. "k": "up"
+	Keys map[string]string `json:"keys,omitempty"`
+	// Terminal theme, empty selects the default one
+	Theme        string               `json:"theme,omitempty"`
+	Stats        Stats                `json:"stats"`
+	Achievements achievements.Unlocks `json:"achievements"`
+	Scores       highscores.Table     `json:"scores"`
+}
+
+// Totals over every game played with the profile
+type Stats struct {
+	Games     int           `json:"games"`
+	Wins      int           `json:"wins"`
+	Eaten     int           `json:"eaten"`
+	BestScore int           `json:"bestScore"`
+	PlayTime  time.Duration `json:"playTime"`
+
+	// Game time of the last event of the current game, resumed games don't start at zero
+	elapsed time.Duration
+	started bool
+}
+
+// Every profile of the machine
+type Store struct {
+	Profiles []*Profile `json:"profiles"`
+	// Profile picked last time, offered first by the picker
+	Last string `json:"last,omitempty"`
+}
+
+// Commands of the binding actions
+var actions = map[string]sg.Command{
+	"up":         sg.Turn(sg.DirectionUp),
+	"right":      sg.Turn(sg.DirectionRight),
+	"down":       sg.Turn(sg.DirectionDown),
+	"left":       sg.Turn(sg.DirectionLeft),
+	"up-left":    sg.Turn(sg.DirectionUpLeft),
+	"up-right":   sg.Turn(sg.DirectionUpRight),
+	"down-left":  sg.Turn(sg.DirectionDownLeft),
+	"down-right": sg.Turn(sg.DirectionDownRight),
+	"rewind":     {Kind: sg.CommandRewind},
+	"resume":     {Kind: sg.CommandResume},
+	"quit":       {Kind: sg.CommandQuit},
+}
+
+// Read the profiles, an empty input gives none
+func Load(r io.Reader) (*Store, error) {
+	store := &Store{}
+	if err := json.NewDecoder(r).Decode(store); err != nil && err != io.EOF {
+		return nil, err
+	}
+	for _, p := range store.Profiles {
+		p.init()
+		if _, err := p.Bindings(); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+		}
+	}
+	return store, nil
+}
+
+// Write the profiles
+func (s *Store) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(s)
+}
+
+// Profile with the name, nil if there is none
+func (s *Store) Get(name string) *Profile {
+	i := slices.IndexFunc(s.Profiles, func(p *Profile) bool {
+		return p.Name == name
+	})
+	if i < 0 {
+		return nil
+	}
+	return s.Profiles[i]
+}
+
+// Achievements file kept per player name before the profiles
+type legacyAchievements struct {
+	Players map[string]achievements.Unlocks `json:"players"`
+}
+
+// Add the unlocks of the old achievements file to the profiles of the same names,
+// creating the missing ones. Importing the file again changes nothing
+func (s *Store) ImportAchievements(r io.Reader) error {
+	var legacy legacyAchievements
+	if err := json.NewDecoder(r).Decode(&legacy); err != nil && err != io.EOF {
+		return err
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(legacy.Players)) {
+		p := s.Get(name)
+		if p == nil {
+			var err error
+			if p, err = s.Create(name); err != nil {
+				errs = append(errs, fmt.Errorf("player %q: %w", name, err))
+				continue
+			}
+		}
+		for id, date := range legacy.Players[name] {
+			p.Achievements.Unlock(id, date)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Add an empty profile with the name
+func (s *Store) Create(name string) (*Profile, error) {
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return nil, fmt.Errorf("profile name needs 1 to %d characters", maxNameLength)
+	}
+	if s.Get(name) != nil {
+		return nil, fmt.Errorf("profile %s already exists", name)
+	}
+	p := &Profile{Name: name}
+	p.init()
+	s.Profiles = append(s.Profiles, p)
+	return p, nil
+}
+
+// Make the maps of a new or loaded profile usable
+func (p *Profile) init() {
+	if p.Achievements == nil {
+		p.Achievements = make(achievements.Unlocks)
+	}
+	if p.Scores.Modes == nil {
+		p.Scores.Modes = make(map[string][]highscores.Entry)
+	}
+}
+
+// Bind the key character to the action, an empty action removes the binding
+func (p *Profile) Bind(key string, action string) error {
+	if utf8.RuneCountInString(key) != 1 {
+		return fmt.Errorf("key %q must be a single character", key)
+	}
+	if action == "" {
+		delete(p.Keys, key)
+		return nil
+	}
+	if _, ok := actions[action]; !ok {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	if p.Keys == nil {
+		p.Keys = make(map[string]string)
+	}
+	p.Keys[key] = action
+	return nil
+}
+
+// Commands of the key bindings, they take precedence over the default keys
+func (p *Profile) Bindings() (map[rune]sg.Command, error) {
+	bindings := make(map[rune]sg.Command, len(p.Keys))
+	for key, action := range p.Keys {
+		r, size := utf8.DecodeRuneInString(key)
+		if size != len(key) || size == 0 {
+			return nil, fmt.Errorf("key %q must be a single character", key)
+		}
+		command, ok := actions[action]
+		if !ok {
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+		bindings[r] = command
+	}
+	return bindings, nil
+}
+
+// Names of the binding actions
+func Actions() []string {
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Count the game events, matches sg.EventHandler
+func (s *Stats) Handle(e sg.Event) {
+	if e.Kind == sg.EventEat {
+		s.Eaten++
+	}
+	if s.started && e.Elapsed > s.elapsed {
+		s.PlayTime += e.Elapsed - s.elapsed
+	}
+	s.elapsed, s.started = e.Elapsed, true
+}
+
+// Count the finished game
+func (s *Stats) Record(result sg.Result) {
+	s.Games++
+	if result.Outcome == sg.OutcomeWon {
+		s.Wins++
+	}
+	s.BestScore = max(s.BestScore, result.Score)
+}
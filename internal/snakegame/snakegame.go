@@ -1,38 +1,141 @@
 package snakegame
 
 import (
-	"math/rand"
+	"slices"
 	"time"
 )
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
+// Game speed
+const TicksPerSecond = 5
+
+// Largest supported board side
+const MaxBoardSize = 1 << 20
+
+// Largest board area materialized for the display by default
+const DefaultViewportSize = 100
 
 // Main snake game structure
 type SnakeGame struct {
-	board board
-	food  vertex
-	snake []vertex
+	board    board
+	food     Point
+	snake    snake
+	overlaps map[Point]int
+	rivals   []rival
+
+	level         *Level
+	portalPairs   []PortalPair
+	portals       map[Point]Point
+	randomPortals int
+	walls         []Point
+	exit          *Point
+
+	entities      []Entity
+	randomBalls   int
+	randomChasers int
+
+	foodSpawner FoodSpawner
+	foodSpawned int
+	eaten       int
+
+	powerUps PowerUpConfig
+	powerUp  *powerUp
+	effects  [powerUpKinds]int
 
 	moveDirection Direction
-	turnDirection chan Direction
-
-	ateFood      bool
-	borderKiller bool
-	gameOver     bool
-	quit         chan bool
+	commands      chan Command
+
+	collapsed       int
+	nextCollapse    int
+	collapseWarning int
+
+	growth   int
+	mode     Mode
+	elapsed  time.Duration
+	rules    []Rule
+	grid     Grid
+	topology Topology
+	gameOver bool
+	won      bool
+	wonBy    EventKind
+	quit     bool
+
+	practice  bool
+	paused    bool
+	history   history
+	turnBased bool
+	advance   bool
+	held      *Command
+
+	score        int
+	tick         int
+	tickInterval time.Duration
+	random       random
+
+	steps     int
+	recording bool
+	inputLog  InputLog
+	ghostLog  *InputLog
+	ghost     *ghost
+	ghostRun  bool
+
+	eventHandlers []EventHandler
+
+	autosaveInterval int
+	autosave         func(game *SnakeGame)
+
+	viewportSize Point
 
 	display    DisplayFunc
 	keyHandler KeyHandlerFunc
 }
 
+// Optional game setting applied during initialization
+type Option func(game *SnakeGame)
+
+// Seed the game random generator to get reproducible food placement
+func WithSeed(seed int64) Option {
+	return func(game *SnakeGame) {
+		game.random.seed(seed)
+	}
+}
+
+// Call autosave every interval ticks while the game is running
+func WithAutosave(interval int, autosave func(game *SnakeGame)) Option {
+	return func(game *SnakeGame) {
+		game.autosaveInterval = interval
+		game.autosave = autosave
+	}
+}
+
+// Practice mode: keep the last historySize ticks to rewind, scores are not ranked
+func WithPractice(historySize int) Option {
+	return func(game *SnakeGame) {
+		game.practice = true
+		game.history.init(historySize)
+	}
+}
+
+// Turn-based mode: the game advances a tick per direction key instead of a timer,
+// the last undoSize ticks can be undone with the rewind command
+func WithTurnBased(undoSize int) Option {
+	return func(game *SnakeGame) {
+		game.turnBased = true
+		game.history.init(undoSize)
+	}
+}
+
+// Limit the area passed to the display, it follows the snake head on larger boards
+func WithViewport(hight int, width int) Option {
+	return func(game *SnakeGame) {
+		game.viewportSize = Point{X: width, Y: hight}
+	}
+}
+
 // Initialization
-func (game *SnakeGame) Init(boardHight int, boardWidth int, borderKiller bool, display DisplayFunc, keyHandler KeyHandlerFunc) {
-	if boardHight > 100 || boardHight < 0 || boardWidth > 100 || boardWidth < 0 {
-		panic("Expected board size [0-100]:[0-100] was not satisfied")
+func (game *SnakeGame) Init(boardHight int, boardWidth int, borderKiller bool, display DisplayFunc, keyHandler KeyHandlerFunc, options ...Option) {
+	if boardHight > MaxBoardSize || boardHight <= 0 || boardWidth > MaxBoardSize || boardWidth <= 0 {
+		panic("Expected board size [1-1048576]:[1-1048576] was not satisfied")
 	}
-	game.board.init(uint8(boardHight), uint8(boardWidth))
 
 	if display == nil || keyHandler == nil {
 		panic("Both of keyHandler and display should be specified")
@@ -40,47 +143,107 @@ func (game *SnakeGame) Init(boardHight int, boardWidth int, borderKiller bool, d
 	game.keyHandler = keyHandler
 	game.display = display
 
-	game.turnDirection = make(chan Direction, 10)
-	game.quit = make(chan bool, 1)
-	game.moveDirection = DirectionUp
-	game.snake = []vertex{{game.board.width / 2, game.board.hight / 2}}
-	game.borderKiller = borderKiller
-	game.generateFood()
+	game.random.seed(time.Now().UnixNano())
+	game.foodSpawner = UniformSpawner{}
+	game.rules = DefaultRules()
+	game.mode = Classic{}
+	game.tickInterval = time.Second / TicksPerSecond
+	game.grid = SquareGrid{}
+	game.topology = Torus
+	if borderKiller {
+		game.topology = Walled
+	}
+	for _, option := range options {
+		option(game)
+	}
+	if game.ghostLog != nil && !game.ghostRun {
+		game.ghost = newGhost(*game.ghostLog, boardHight, boardWidth, borderKiller, options)
+	}
+	if game.level != nil {
+		boardHight, boardWidth = game.level.Hight, game.level.Width
+	}
+	game.board.init(boardHight, boardWidth, game.viewportSize)
+	game.board.alignRows = game.grid == Grid(HexGrid{})
+	game.placeLayout()
+	game.overlaps = make(map[Point]int)
+
+	game.commands = make(chan Command, 10)
+	game.moveDirection, _ = game.grid.Resolve(DirectionUp, DirectionUp)
+	start := []Point{{game.board.width / 2, game.board.hight / 2}}
+	if game.level != nil && len(game.level.Snake) > 0 {
+		start = game.level.Snake
+		game.moveDirection, _ = game.grid.Resolve(game.level.Direction, game.level.Direction)
+	} else if spawns := game.spawns(); len(spawns) > 0 {
+		start = []Point{spawns[0].At}
+		game.moveDirection, _ = game.grid.Resolve(spawns[0].Direction, spawns[0].Direction)
+	} else if len(game.rivals) > 0 {
+		start = []Point{{game.board.width / (len(game.rivals) + 2), game.board.hight / 2}}
+	}
+	game.placeSnake(start)
+	game.spawnRivals()
+	game.generatePortals(game.randomPortals)
+	game.generateEnemies()
+	game.refillFood()
 }
 
 // Run main loop
-func (game *SnakeGame) Run() int {
+func (game *SnakeGame) Run() Result {
 	game.runControllerThread()
 
 	for {
-		game.calculateIteration()
-		if game.isQuit() || game.gameOver {
-			return len(game.snake) - 1
+		if result, over := game.step(); over {
+			return result
 		}
 
 		game.refreshBoard()
 		game.printBoard()
-		time.Sleep(time.Second / 5)
+		if game.turnBased {
+			game.awaitCommand()
+		} else {
+			time.Sleep(game.interval())
+		}
 	}
 }
 
-// Fill board matrix with zero values
-func (b *board) clean() {
-	for i := range b.matrix {
-		for j := range b.matrix[i] {
-			b.matrix[i][j] = CellEmpty
+// Single main loop iteration without the display and the wait, true once the game is over
+func (game *SnakeGame) step() (Result, bool) {
+	game.handleCommands()
+	if game.quit {
+		return Result{Score: game.score, Outcome: OutcomeQuit}, true
+	}
+
+	if !game.paused && !game.won && (!game.turnBased || game.advance) {
+		game.advance = false
+		if game.Rewindable() {
+			game.history.push(game.snapshot())
+		}
+		game.calculateIteration()
+		if game.gameOver && game.mode.Respawn(RuleContext{game: game}) {
+			game.respawn()
+		}
+		if game.ghost != nil {
+			game.ghost.catchUp(game.tick)
 		}
 	}
-}
+	if game.won {
+		return Result{Score: game.score, Outcome: OutcomeWon, WonBy: game.wonBy}, true
+	}
+	// A crash on the last tick of a limited mode is still a crash and may be rewound
+	if game.gameOver {
+		// Practice and turn-based games stay on the fatal tick waiting for a rewind
+		if !game.Rewindable() || game.history.empty() {
+			return Result{Score: game.score, Outcome: game.crashOutcome()}, true
+		}
+		game.paused = true
+	} else if game.mode.Over(RuleContext{game: game}) {
+		return Result{Score: game.score, Outcome: OutcomeTimeUp}, true
+	}
 
-// Board initializaion
-func (b *board) init(boardHight uint8, boardWidth uint8) {
-	b.hight = boardHight
-	b.width = boardWidth
-	b.matrix = make([][]Cell, boardHight)
-	for i := range b.matrix {
-		b.matrix[i] = make([]Cell, boardWidth)
+	if !game.paused && game.autosave != nil && game.autosaveInterval > 0 && game.tick%game.autosaveInterval == 0 {
+		game.autosave(game)
 	}
+	game.steps++
+	return Result{}, false
 }
 
 // Print board matrix
@@ -88,7 +251,28 @@ func (game *SnakeGame) printBoard() {
 	if game.keyHandler == nil {
 		panic("Display method is not initialized")
 	}
-	game.display(game.board.matrix, len(game.snake)-1)
+	hud := HUD{Effects: game.activeEffects()}
+	game.mode.HUD(RuleContext{game: game}, &hud)
+	hud.Crashed = game.gameOver
+	if len(game.rivals) > 0 {
+		hud.Players = game.Standings()
+	}
+	if game.ghost != nil {
+		hud.Ghost = true
+		hud.GhostDelta = game.score - game.ghost.game.score
+	}
+	game.display(game.board.matrix, game.score, hud)
+}
+
+// Block until the player sends a command, it's applied by the next step
+func (game *SnakeGame) awaitCommand() {
+	command := <-game.commands
+	game.held = &command
+}
+
+// Check if the ticks can be rewound, e.g. in practice and turn-based games
+func (game *SnakeGame) Rewindable() bool {
+	return game.practice || game.turnBased
 }
 
 // Run key-handler thread
@@ -96,163 +280,285 @@ func (game *SnakeGame) runControllerThread() {
 	if game.keyHandler == nil {
 		panic("Controller method is not initialized")
 	}
-	go game.keyHandler(game.quit, game.turnDirection)
+	go game.keyHandler(game.commands)
 }
 
-// Update internal board-matrix with actual snake and food coordinates
+// Keep the snake head visible, the board itself is updated incrementally
 func (game *SnakeGame) refreshBoard() {
-	game.board.clean()
-	for i, v := range game.snake {
+	game.board.follow(game.snake.headPoint())
+	game.eraseGhost()
+	game.drawTelegraph()
+	game.drawGhost()
+	game.drawEntities()
+}
+
+// Put the snake on the board, head first
+func (game *SnakeGame) placeSnake(points []Point) {
+	game.snake.reset(points)
+	for i := len(points) - 1; i >= 0; i-- {
 		if i == 0 {
-			game.board.matrix[v.y][v.x] = CellSnakeHead
+			game.occupy(points[i], CellSnakeHead)
 		} else {
-			game.board.matrix[v.y][v.x] = CellSnakeTail
+			game.occupy(points[i], CellSnakeTail)
 		}
 	}
-
-	game.board.matrix[game.food.y][game.food.x] = CellFood
 }
 
-// Calculate and update the internal board matrix
+// Calculate and update the internal board matrix, the events are emitted as they happen
 func (game *SnakeGame) calculateIteration() {
-	// Move snake body and grow if food eaten
-	tailEnd := len(game.snake) - 1
-	for i := tailEnd; i >= 0; i-- {
-		if i == tailEnd && game.ateFood {
-			game.snake = append(game.snake, game.snake[tailEnd])
-			game.ateFood = false
-		}
-		if i > 0 {
-			game.snake[i] = game.snake[i-1]
-		}
+	defer game.emitTickEnd()
+	game.tick++
+	game.elapsed += game.tickInterval
+	game.tickPowerUps()
+	game.tickZone()
+	if game.gameOver || game.won {
+		return
+	}
+	game.mode.OnTick(RuleContext{game: game})
+	game.applyRules(func(rule Rule, ctx RuleContext) { rule.OnTick(ctx) })
+	game.applyRules(func(rule Rule, ctx RuleContext) { rule.BeforeMove(ctx) })
+
+	// Every snake moves at once, the rivals pick their heads before the player's snake moves
+	moves := game.planRivals()
+	target := game.moveSnake(moves)
+	game.moveRivals(moves, target)
+}
+
+// Move the player's snake and the entities, then check the food.
+// Returns the move the snake headed for, even if it crashed
+func (game *SnakeGame) moveSnake(moves []rivalMove) rivalMove {
+	// Check if faced with the border, the snake stays if it survives
+	head, direction, ok := game.nextHead()
+	target := rivalMove{head: head, direction: direction, ok: ok}
+	if !ok {
+		game.gameOver = game.collide(CollisionBorder, head, true)
+		return target
 	}
+	game.moveDirection = direction
 
-	// Update direction and check if faced with the border
-	game.updateDirection()
-	game.moveSnakeHead()
+	// Check if faced with a wall, the snake stays if it survives
+	if game.board.get(head) == CellWall {
+		game.gameOver = game.collide(CollisionWall, head, true)
+		return target
+	}
 
-	// Check if faced with ourself
-	for _, tail := range game.snake[1:] {
-		if tail == game.snake[0] {
+	// Check if faced with a rival or its next head
+	if cell := game.board.get(head); cell == CellRivalHead || cell == CellRivalTail || game.rivalTarget(moves, head) {
+		if game.collide(CollisionRival, head, true) {
 			game.gameOver = true
-			return
+			return target
 		}
 	}
 
-	// Check if ate the food
-	if game.snake[0] == game.food {
-		game.ateFood = true
-		game.generateFood()
+	// Check if faced with ourself, the tail end moves away unless growing
+	tailEnd := game.snake.at(game.snake.len() - 1)
+	eats := game.board.get(head) == CellFood
+	if cell := game.board.get(head); (cell == CellSnakeHead || cell == CellSnakeTail) && (game.growth > 0 || head != tailEnd) {
+		if game.collide(CollisionSelf, head, !game.effectActive(PowerUpGhost)) {
+			game.gameOver = true
+			return target
+		}
+	}
+
+	// Move snake body and grow if food eaten
+	if game.growth > 0 {
+		game.growth--
+	} else {
+		game.vacate(game.snake.popTail())
+	}
+
+	if game.snake.len() > 0 {
+		game.board.set(game.snake.headPoint(), CellSnakeTail)
+	}
+	game.snake.pushHead(head)
+	game.occupy(head, CellSnakeHead)
+	game.collectPowerUp(head)
+	game.applyRules(func(rule Rule, ctx RuleContext) { rule.AfterMove(ctx) })
+	if game.exit != nil && head == *game.exit {
+		game.win(EventExit)
+		return target
 	}
+
+	// Move entities and check if they got us
+	game.tickEntities()
+	if game.gameOver {
+		return target
+	}
+	if game.effectActive(PowerUpMagnet) && head != game.food {
+		game.pullFood()
+	}
+
+	// Check if ate the food, a missing one comes back once there is room for it,
+	// e.g. after the snake left the shrunk safe zone full
+	if eats {
+		game.eaten++
+		game.applyRules(func(rule Rule, ctx RuleContext) { rule.OnEat(ctx) })
+		game.emit(Event{Kind: EventEat})
+		game.refillFood()
+	} else if game.board.get(game.food) != CellFood {
+		game.refillFood()
+	}
+	return target
 }
 
-// Re-generate food coordinates
-func (game *SnakeGame) generateFood() {
-	var v vertex
-	for {
-		v = vertex{
-			x: (uint8)(rand.Intn(int(game.board.width - 1))),
-			y: (uint8)(rand.Intn(int(game.board.hight - 1))),
-		}
+// Put a snake segment to the cell, segments may overlap if collisions are not fatal
+func (game *SnakeGame) occupy(p Point, cell Cell) {
+	if c := game.board.get(p); c == CellSnakeHead || c == CellSnakeTail || c == CellRivalHead || c == CellRivalTail {
+		game.overlaps[p]++
+	}
+	game.board.set(p, cell)
+}
 
-		// Regenerate if food created "in snake"
-		inSnake := false
-		for _, e := range game.snake {
-			if e == v {
-				inSnake = true
-				break
-			}
-		}
-		if !inSnake {
-			break
+// Remove a snake segment from the cell
+func (game *SnakeGame) vacate(p Point) {
+	if game.overlaps[p] > 0 {
+		game.overlaps[p]--
+		if game.overlaps[p] == 0 {
+			delete(game.overlaps, p)
 		}
+		return
 	}
-	game.food = v
+	// Cells of the collapsed rings left by a snake that survived the collapse turn into walls
+	if game.board.depth(p) < game.collapsed {
+		game.board.set(p, CellWall)
+		return
+	}
+	game.board.set(p, CellEmpty)
 }
 
-// Check and change direction according to signal
-func (game *SnakeGame) updateDirection() {
-	var newDirection Direction
-
+// Apply pending commands: quit, rewind, resume and at most one turn per tick
+func (game *SnakeGame) handleCommands() {
 	for {
-		select {
-		case newDirection = <-game.turnDirection:
-		default:
-			return
+		var command Command
+		if game.held != nil {
+			command, game.held = *game.held, nil
+		} else {
+			select {
+			case command = <-game.commands:
+			default:
+				return
+			}
+		}
+		if game.recording {
+			game.inputLog.Commands = append(game.inputLog.Commands, LoggedCommand{Step: game.steps, Kind: command.Kind, Direction: command.Direction, Player: command.Player})
 		}
 
-		// Ignore inapplicable turn triggers
-		if newDirection == game.moveDirection ||
-			(newDirection == DirectionUp && game.moveDirection == DirectionDown) ||
-			(newDirection == DirectionRight && game.moveDirection == DirectionLeft) ||
-			(newDirection == DirectionDown && game.moveDirection == DirectionUp) ||
-			(newDirection == DirectionLeft && game.moveDirection == DirectionRight) {
-			continue
+		switch command.Kind {
+		case CommandQuit:
+			game.quit = true
+			return
+
+		case CommandRewind:
+			if game.Rewindable() && !game.history.empty() {
+				game.restore(game.history.pop())
+				game.paused = game.practice
+				if game.ghost != nil {
+					game.ghost.rewind(game.tick)
+				}
+			}
+
+		case CommandResume:
+			if !game.gameOver {
+				game.paused = false
+			}
+
+		case CommandTurn:
+			if command.Player != 0 {
+				game.turnRival(command)
+				continue
+			}
+			if game.gameOver {
+				continue
+			}
+			game.paused = false
+
+			// Ignore inapplicable turn triggers
+			newDirection := command.Direction
+			game.applyRules(func(rule Rule, ctx RuleContext) {
+				newDirection = rule.OnTurn(ctx, newDirection)
+			})
+			newDirection, ok := game.grid.Resolve(newDirection, game.moveDirection)
+			if !ok || newDirection == oppositeDirections[game.moveDirection] {
+				continue
+			}
+
+			// Turn-based games step forward on the current direction key as well
+			if newDirection == game.moveDirection && !game.turnBased {
+				continue
+			}
+			if newDirection != game.moveDirection {
+				game.emit(Event{Kind: EventTurn, Direction: newDirection, From: game.moveDirection})
+			}
+			game.moveDirection = newDirection
+			game.advance = true
+			return
 		}
+	}
+}
 
-		game.moveDirection = newDirection
-		break
+// Next snake head position and direction, false if killed by the border
+func (game *SnakeGame) nextHead() (Point, Direction, bool) {
+	head, direction, ok := game.topology.Step(game.grid, game.snake.headPoint(), game.moveDirection, game.board.hight, game.board.width)
+	if ok && direction == oppositeDirections[game.moveDirection] && game.snake.len() > 1 {
+		head, direction, ok = game.reverseBody(&game.snake, game.moveDirection, CellSnakeHead, CellSnakeTail)
 	}
+	if !ok {
+		return head, direction, false
+	}
+	return game.throughPortals(head, direction)
 }
 
-// Move snake head and handle border interaction
-func (game *SnakeGame) moveSnakeHead() {
-	switch game.moveDirection {
-	case DirectionUp:
-		if game.snake[0].y != 0 {
-			game.snake[0].y -= 1
+// Turn a snake moving in the direction around after a bounce straight back, the tail end
+// becomes the head. Returns the next step of the new head keeping on the way the tail was leaving
+func (game *SnakeGame) reverseBody(s *snake, moveDirection Direction, headCell Cell, tailCell Cell) (Point, Direction, bool) {
+	points := s.points()
+	slices.Reverse(points)
+	game.board.set(points[len(points)-1], tailCell)
+	game.board.set(points[0], headCell)
+	s.reset(points)
+
+	hight, width := game.board.hight, game.board.width
+	direction := oppositeDirections[moveDirection]
+	for _, d := range game.grid.Directions() {
+		if p, heading, ok := game.topology.Step(game.grid, points[1], d, hight, width); ok && p == points[0] {
+			direction = heading
 			break
 		}
+	}
+	return game.topology.Step(game.grid, points[0], direction, hight, width)
+}
 
-		if game.borderKiller {
-			game.gameOver = true
-			return
-		}
-		game.snake[0].y = game.board.hight - 1
+// Check if the game ended by collision
+func (game *SnakeGame) IsGameOver() bool {
+	return game.gameOver
+}
 
-	case DirectionRight:
-		if game.snake[0].x != game.board.width-1 {
-			game.snake[0].x += 1
-			break
-		}
+// Cell adjacency of the board
+func (game *SnakeGame) Grid() Grid {
+	return game.grid
+}
 
-		if game.borderKiller {
-			game.gameOver = true
-			return
-		}
-		game.snake[0].x = 0
+// Check that the board edges fit the grid, e.g. after Init with the grid and topology options
+func (game *SnakeGame) CheckGrid() error {
+	return checkGrid(game.grid, game.topology, game.board.hight)
+}
 
-	case DirectionDown:
-		if game.snake[0].y != game.board.hight-1 {
-			game.snake[0].y += 1
-			break
-		}
+// Name of the played game mode
+func (game *SnakeGame) ModeName() string {
+	return game.mode.Name()
+}
 
-		if game.borderKiller {
-			game.gameOver = true
-			return
-		}
-		game.snake[0].y = 0
-	case DirectionLeft:
-		if game.snake[0].x != 0 {
-			game.snake[0].x -= 1
-			break
-		}
+// Check if the game advances on the player input instead of a timer
+func (game *SnakeGame) IsTurnBased() bool {
+	return game.turnBased
+}
 
-		if game.borderKiller {
-			game.gameOver = true
-			return
-		}
-		game.snake[0].x = game.board.width - 1
-	}
+// Check if the game is played in practice mode and must not be ranked
+func (game *SnakeGame) IsPractice() bool {
+	return game.practice
 }
 
-// Exit initiation
-func (game *SnakeGame) isQuit() bool {
-	select {
-	case <-game.quit:
-		return true
-	default:
-		return false
-	}
+// Board size, levels may override the one passed to Init
+func (game *SnakeGame) Size() (hight int, width int) {
+	return game.board.hight, game.board.width
 }
@@ -0,0 +1,100 @@
+package snakegame
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+// Initialized game without display and keyboard
+func headlessGame(hight int, width int, options ...Option) *SnakeGame {
+	game := &SnakeGame{}
+	game.Init(hight, width, false, func([][]Cell, int, HUD) {}, func(chan<- Command) {}, options...)
+	return game
+}
+
+// Play the steps turning at random like a player would, the rivals steered by humans
+// included, and quit after them unless the game is over before
+func playScripted(game *SnakeGame, steps int, seed uint64) Result {
+	r := rand.New(rand.NewPCG(seed, seed))
+	turns := []Direction{DirectionUp, DirectionRight, DirectionDown, DirectionLeft}
+	for i := 0; i < steps; i++ {
+		for player := 0; player <= len(game.rivals); player++ {
+			if r.IntN(3) == 0 {
+				game.commands <- Command{Kind: CommandTurn, Direction: turns[r.IntN(len(turns))], Player: player}
+			}
+		}
+		if result, over := game.step(); over {
+			return result
+		}
+	}
+	game.commands <- Command{Kind: CommandQuit}
+	result, _ := game.step()
+	return result
+}
+
+// Headless game with a straight snake of the length heading right along the middle row,
+// on the torus it never meets its own tail and the only food stays off its row
+func benchmarkGame(b *testing.B, hight int, width int, length int) *SnakeGame {
+	level := &Level{
+		Hight:     hight,
+		Width:     width,
+		Direction: DirectionRight,
+		Food:      FoodConfig{Strategy: "scripted", Points: []Point{{X: 0, Y: 0}}},
+	}
+	for x := length - 1; x >= 0; x-- {
+		level.Snake = append(level.Snake, Point{X: x, Y: hight / 2})
+	}
+	if err := level.Validate(); err != nil {
+		b.Fatal(err)
+	}
+
+	game := &SnakeGame{}
+	game.Init(hight, width, false, func([][]Cell, int, HUD) {}, func(chan<- Command) {}, WithLevel(level), WithSeed(1))
+	return game
+}
+
+// Cost of a tick doesn't depend on the snake length nor the board area
+func BenchmarkStep(b *testing.B) {
+	for _, size := range []int{256, 2048, 16384} {
+		for _, length := range []int{16, 1024, 16000} {
+			if length >= size {
+				continue
+			}
+			b.Run(fmt.Sprintf("board=%dx%d/length=%d", size, size, length), func(b *testing.B) {
+				game := benchmarkGame(b, size, size, length)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, over := game.step(); over {
+						b.Fatal("benchmark snake died")
+					}
+				}
+			})
+		}
+	}
+}
+
+// Picking a food cell is constant-time while at least half of the board is free,
+// on denser boards the chunk walk grows with the board area
+func BenchmarkRandomFree(b *testing.B) {
+	for _, size := range []int{256, 1024, 4096} {
+		for _, filled := range []int{25, 75} {
+			b.Run(fmt.Sprintf("board=%dx%d/filled=%d%%", size, size, filled), func(b *testing.B) {
+				game := benchmarkGame(b, size, size, 1)
+				for y := 1; y < size*filled/100; y++ {
+					for x := 0; x < size; x++ {
+						if p := (Point{X: x, Y: y}); game.board.get(p) == CellEmpty {
+							game.board.set(p, CellWall)
+						}
+					}
+				}
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, ok := (arena{game}).RandomFree(); !ok {
+						b.Fatal("no free cell")
+					}
+				}
+			})
+		}
+	}
+}
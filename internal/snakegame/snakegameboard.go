@@ -0,0 +1,152 @@
+package snakegame
+
+// Board initializaion, zero viewport size selects the default one
+func (b *board) init(boardHight int, boardWidth int, viewportSize Point) {
+	b.hight = boardHight
+	b.width = boardWidth
+	b.chunks = make(map[Point]*chunk)
+	b.used = 0
+
+	if viewportSize.X <= 0 || viewportSize.X > boardWidth {
+		viewportSize.X = min(boardWidth, DefaultViewportSize)
+	}
+	if viewportSize.Y <= 0 || viewportSize.Y > boardHight {
+		viewportSize.Y = min(boardHight, DefaultViewportSize)
+	}
+	b.viewportOrigin = Point{}
+	b.matrix = make([][]Cell, viewportSize.Y)
+	for i := range b.matrix {
+		b.matrix[i] = make([]Cell, viewportSize.X)
+	}
+}
+
+// Remove everything from the board
+func (b *board) clean() {
+	b.chunks = make(map[Point]*chunk)
+	b.used = 0
+	for i := range b.matrix {
+		for j := range b.matrix[i] {
+			b.matrix[i][j] = CellEmpty
+		}
+	}
+}
+
+// Check if the point lies on the board
+func (b *board) contains(p Point) bool {
+	return p.X >= 0 && p.X < b.width && p.Y >= 0 && p.Y < b.hight
+}
+
+// Cell at the point
+func (b *board) get(p Point) Cell {
+	c, ok := b.chunks[Point{X: p.X / chunkSize, Y: p.Y / chunkSize}]
+	if !ok {
+		return CellEmpty
+	}
+	return c.cells[(p.Y%chunkSize)*chunkSize+p.X%chunkSize]
+}
+
+// Change cell at the point, chunks are released once they become empty
+func (b *board) set(p Point, cell Cell) {
+	key := Point{X: p.X / chunkSize, Y: p.Y / chunkSize}
+	c, ok := b.chunks[key]
+	if !ok {
+		if cell == CellEmpty {
+			return
+		}
+		c = &chunk{}
+		b.chunks[key] = c
+	}
+
+	i := (p.Y%chunkSize)*chunkSize + p.X%chunkSize
+	if c.cells[i] == CellEmpty && cell != CellEmpty {
+		c.used++
+		b.used++
+	} else if c.cells[i] != CellEmpty && cell == CellEmpty {
+		c.used--
+		b.used--
+	}
+	c.cells[i] = cell
+	if c.used == 0 {
+		delete(b.chunks, key)
+	}
+
+	b.draw(p, cell)
+}
+
+// Put the cell to the viewport matrix if it is visible
+func (b *board) draw(p Point, cell Cell) {
+	x, y := p.X-b.viewportOrigin.X, p.Y-b.viewportOrigin.Y
+	if y >= 0 && y < len(b.matrix) && x >= 0 && x < len(b.matrix[y]) {
+		b.matrix[y][x] = cell
+	}
+}
+
+// Re-center the viewport once the point gets close to its edge,
+// the matrix is redrawn only when the viewport actually moves
+func (b *board) follow(p Point) {
+	viewportHight, viewportWidth := len(b.matrix), len(b.matrix[0])
+	x, y := p.X-b.viewportOrigin.X, p.Y-b.viewportOrigin.Y
+	if x >= viewportWidth/4 && x < viewportWidth-viewportWidth/4 &&
+		y >= viewportHight/4 && y < viewportHight-viewportHight/4 {
+		return
+	}
+
+	origin := Point{
+		X: max(0, min(p.X-viewportWidth/2, b.width-viewportWidth)),
+		Y: max(0, min(p.Y-viewportHight/2, b.hight-viewportHight)),
+	}
+	if b.alignRows {
+		origin.Y -= origin.Y % 2
+	}
+	if origin == b.viewportOrigin {
+		return
+	}
+
+	b.viewportOrigin = origin
+	for i := range b.matrix {
+		for j := range b.matrix[i] {
+			b.matrix[i][j] = b.get(Point{X: origin.X + j, Y: origin.Y + i})
+		}
+	}
+}
+
+// Number of empty cells
+func (b *board) free() int {
+	return b.hight*b.width - b.used
+}
+
+// Find the n-th empty cell in row-major order of chunks,
+// fully empty or full chunks are skipped without looking inside, so the walk
+// still grows with the number of chunks of the board
+func (b *board) nthFree(n int) Point {
+	for chunkY := 0; chunkY*chunkSize < b.hight; chunkY++ {
+		for chunkX := 0; chunkX*chunkSize < b.width; chunkX++ {
+			origin := Point{X: chunkX * chunkSize, Y: chunkY * chunkSize}
+			chunkHight := min(chunkSize, b.hight-origin.Y)
+			chunkWidth := min(chunkSize, b.width-origin.X)
+
+			c := b.chunks[Point{X: chunkX, Y: chunkY}]
+			free := chunkHight * chunkWidth
+			if c != nil {
+				free -= c.used
+			}
+			if n >= free {
+				n -= free
+				continue
+			}
+
+			for y := 0; y < chunkHight; y++ {
+				for x := 0; x < chunkWidth; x++ {
+					if c != nil && c.cells[y*chunkSize+x] != CellEmpty {
+						continue
+					}
+					if n == 0 {
+						return Point{X: origin.X + x, Y: origin.Y + y}
+					}
+					n--
+				}
+			}
+		}
+	}
+	panic("Free cell index is out of range")
+}
@@ -0,0 +1,391 @@
+package snakegame
+
+import (
+	"fmt"
+	"slices"
+)
+
+// What happens to the snake touching an entity
+type EntityEffect int8
+
+const (
+	EffectKill EntityEffect = iota
+	EffectShrink
+)
+
+// Object moving on its own every tick
+type Entity interface {
+	// Advance by one tick
+	Tick(world World)
+	// Cells covered right now
+	Cells() []Point
+	// How the entity is displayed
+	Look() Cell
+	// Effect of touching the snake head and the number of segments to shrink by
+	Effect() (EntityEffect, int)
+	// Independent copy for history snapshots
+	Clone() Entity
+}
+
+// Read-only view of the game given to entities
+type World interface {
+	Size() (hight int, width int)
+	Tick() int
+	Head() Point
+	// Check if the cell stops moving entities, e.g. a wall or the outside of the board
+	Blocked(p Point) bool
+	// Deterministic random value in [0, n)
+	Intn(n int) int
+}
+
+// Serializable entity description, e.g. as a part of a level
+type EntityConfig struct {
+	Type     string `json:"type"`
+	Effect   string `json:"effect,omitempty"`
+	Shrink   int    `json:"shrink,omitempty"`
+	Position Point  `json:"position"`
+
+	// Patrol
+	Path   []Point `json:"path,omitempty"`
+	Length int     `json:"length,omitempty"`
+	Offset int     `json:"offset,omitempty"`
+
+	// Ball
+	Velocity Point `json:"velocity"`
+
+	// Chaser
+	Every int `json:"every,omitempty"`
+}
+
+// Touch behavior shared by the built-in entities
+type Contact struct {
+	Kill   bool
+	Shrink int
+}
+
+func (c Contact) Effect() (EntityEffect, int) {
+	if c.Kill {
+		return EffectKill, 0
+	}
+	return EffectShrink, max(c.Shrink, 1)
+}
+
+// Build the entity described by the config
+func (c EntityConfig) Entity() (Entity, error) {
+	var contact Contact
+	switch c.Effect {
+	case "", "kill":
+		contact.Kill = true
+	case "shrink":
+		contact.Shrink = c.Shrink
+	default:
+		return nil, fmt.Errorf("unknown entity effect %q", c.Effect)
+	}
+
+	switch c.Type {
+	case "patrol":
+		if len(c.Path) == 0 {
+			return nil, fmt.Errorf("patrol needs a path")
+		}
+		return &Patrol{Contact: contact, Path: c.Path, Length: c.Length, Offset: c.Offset}, nil
+	case "ball":
+		return &Ball{Contact: contact, Position: c.Position, Velocity: c.Velocity}, nil
+	case "chaser":
+		return &Chaser{Contact: contact, Position: c.Position, Every: c.Every}, nil
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", c.Type)
+	}
+}
+
+// Cells the entity is placed on or moves along, they must lie on the board
+func (c EntityConfig) Points() []Point {
+	if c.Type == "patrol" {
+		return c.Path
+	}
+	return []Point{c.Position}
+}
+
+// Wall segment sliding back and forth along the path
+type Patrol struct {
+	Contact
+	Path   []Point
+	Length int
+	Offset int
+}
+
+func (e *Patrol) Tick(world World) {
+	e.Offset++
+}
+
+// Segment start ping-pongs over the path positions it fits in
+func (e *Patrol) Cells() []Point {
+	length := max(1, min(e.Length, len(e.Path)))
+	span := len(e.Path) - length
+	start := 0
+	if span > 0 {
+		start = e.Offset % (2 * span)
+		if start > span {
+			start = 2*span - start
+		}
+	}
+	return e.Path[start : start+length]
+}
+
+func (e *Patrol) Look() Cell {
+	return CellObstacle
+}
+
+func (e *Patrol) Clone() Entity {
+	clone := *e
+	return &clone
+}
+
+// Ball flying diagonally and bouncing off the board edges and walls
+type Ball struct {
+	Contact
+	Position Point
+	Velocity Point
+}
+
+// A blocked cell beside the way flips the velocity across it, a blocked corner flips both
+func (e *Ball) Tick(world World) {
+	next := Point{X: e.Position.X + e.Velocity.X, Y: e.Position.Y + e.Velocity.Y}
+	blockedX := world.Blocked(Point{X: next.X, Y: e.Position.Y})
+	blockedY := world.Blocked(Point{X: e.Position.X, Y: next.Y})
+	if blockedX || !blockedY && world.Blocked(next) {
+		e.Velocity.X = -e.Velocity.X
+	}
+	if blockedY || !blockedX && world.Blocked(next) {
+		e.Velocity.Y = -e.Velocity.Y
+	}
+	if next = (Point{X: e.Position.X + e.Velocity.X, Y: e.Position.Y + e.Velocity.Y}); !world.Blocked(next) {
+		e.Position = next
+	}
+}
+
+func (e *Ball) Cells() []Point {
+	return []Point{e.Position}
+}
+
+func (e *Ball) Look() Cell {
+	return CellEnemy
+}
+
+func (e *Ball) Clone() Entity {
+	clone := *e
+	return &clone
+}
+
+// Enemy stepping toward the snake head every few ticks
+type Chaser struct {
+	Contact
+	Position Point
+	Every    int
+}
+
+func (e *Chaser) Tick(world World) {
+	if e.Every > 1 && world.Tick()%e.Every != 0 {
+		return
+	}
+
+	head := world.Head()
+	dx, dy := head.X-e.Position.X, head.Y-e.Position.Y
+	moveX := max(dx, -dx) > max(dy, -dy)
+	if max(dx, -dx) == max(dy, -dy) {
+		moveX = world.Intn(2) == 0
+	}
+
+	// A blocked step goes along the other axis, or nowhere
+	for _, x := range []bool{moveX, !moveX} {
+		next := e.Position
+		switch {
+		case x && dx > 0:
+			next.X++
+		case x && dx < 0:
+			next.X--
+		case !x && dy > 0:
+			next.Y++
+		case !x && dy < 0:
+			next.Y--
+		}
+		if next != e.Position && !world.Blocked(next) {
+			e.Position = next
+			return
+		}
+	}
+}
+
+func (e *Chaser) Cells() []Point {
+	return []Point{e.Position}
+}
+
+func (e *Chaser) Look() Cell {
+	return CellEnemy
+}
+
+func (e *Chaser) Clone() Entity {
+	clone := *e
+	return &clone
+}
+
+// Describe a built-in entity for saving
+func entityConfig(entity Entity) (EntityConfig, error) {
+	var c EntityConfig
+	var contact Contact
+	switch e := entity.(type) {
+	case *Patrol:
+		c = EntityConfig{Type: "patrol", Path: e.Path, Length: e.Length, Offset: e.Offset}
+		contact = e.Contact
+	case *Ball:
+		c = EntityConfig{Type: "ball", Position: e.Position, Velocity: e.Velocity}
+		contact = e.Contact
+	case *Chaser:
+		c = EntityConfig{Type: "chaser", Position: e.Position, Every: e.Every}
+		contact = e.Contact
+	default:
+		return c, fmt.Errorf("entity %T can't be saved", entity)
+	}
+	if !contact.Kill {
+		c.Effect, c.Shrink = "shrink", contact.Shrink
+	}
+	return c, nil
+}
+
+// Add the entities to the game
+func WithEntities(entities ...Entity) Option {
+	return func(game *SnakeGame) {
+		game.entities = append(game.entities, entities...)
+	}
+}
+
+// Add bouncing balls and chasers at random positions derived from the seed
+func WithRandomEnemies(balls int, chasers int) Option {
+	return func(game *SnakeGame) {
+		game.randomBalls = balls
+		game.randomChasers = chasers
+	}
+}
+
+// World implementation backed by the game
+type world struct {
+	game *SnakeGame
+}
+
+func (w world) Size() (int, int) {
+	return w.game.board.hight, w.game.board.width
+}
+
+func (w world) Tick() int {
+	return w.game.tick
+}
+
+func (w world) Head() Point {
+	return w.game.snake.headPoint()
+}
+
+func (w world) Blocked(p Point) bool {
+	if !w.game.board.contains(p) {
+		return true
+	}
+	switch w.game.board.get(p) {
+	case CellWall, CellObstacle, CellPortal, CellExit:
+		return true
+	}
+	return false
+}
+
+func (w world) Intn(n int) int {
+	return w.game.random.intn(n)
+}
+
+// Number of cells covered by the entities
+func (game *SnakeGame) entityCells() int {
+	cells := 0
+	for _, entity := range game.entities {
+		cells += len(entity.Cells())
+	}
+	return cells
+}
+
+// Check if an entity covers the cell
+func (game *SnakeGame) underEntity(p Point) bool {
+	for _, entity := range game.entities {
+		if slices.Contains(entity.Cells(), p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Place random enemies on free cells
+func (game *SnakeGame) generateEnemies() {
+	diagonals := []Point{{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+	for i := 0; i < game.randomBalls; i++ {
+		if p, ok := (arena{game}).RandomFree(); ok {
+			game.entities = append(game.entities, &Ball{Contact: Contact{Kill: true}, Position: p, Velocity: diagonals[game.random.intn(len(diagonals))]})
+		}
+	}
+	for i := 0; i < game.randomChasers; i++ {
+		if p, ok := (arena{game}).RandomFree(); ok {
+			game.entities = append(game.entities, &Chaser{Contact: Contact{Shrink: 1}, Position: p, Every: 3})
+		}
+	}
+}
+
+// Move entities and apply their effect if they touch the snake head, before or after
+// their move so a head stepping onto an entity leaving the cell or swapping cells with it counts
+func (game *SnakeGame) tickEntities() {
+	game.eraseEntities()
+	before := make([][]Point, len(game.entities))
+	for i, entity := range game.entities {
+		before[i] = slices.Clone(entity.Cells())
+		entity.Tick(world{game})
+	}
+	game.drawEntities()
+
+	head := game.snake.headPoint()
+	for i, entity := range game.entities {
+		if !slices.Contains(before[i], head) && !slices.Contains(entity.Cells(), head) {
+			continue
+		}
+
+		effect, segments := entity.Effect()
+		if game.collide(CollisionEntity, head, effect == EffectKill) {
+			game.gameOver = true
+			return
+		}
+		if effect == EffectShrink {
+			game.shrink(segments)
+			game.drawEntities()
+		}
+	}
+}
+
+// Restore the board cells covered by the entities in the viewport
+func (game *SnakeGame) eraseEntities() {
+	for _, entity := range game.entities {
+		for _, p := range entity.Cells() {
+			game.board.draw(p, game.board.get(p))
+		}
+	}
+}
+
+// Draw the entities over the board in the viewport
+func (game *SnakeGame) drawEntities() {
+	for _, entity := range game.entities {
+		for _, p := range entity.Cells() {
+			game.board.draw(p, entity.Look())
+		}
+	}
+}
+
+// Cut segments from the tail, the game is over if nothing is left
+func (game *SnakeGame) shrink(segments int) {
+	for i := 0; i < segments; i++ {
+		if game.snake.len() <= 1 {
+			game.gameOver = true
+			return
+		}
+		game.vacate(game.snake.popTail())
+	}
+}
@@ -0,0 +1,72 @@
+package snakegame
+
+import (
+	"testing"
+)
+
+// Game with the snake heading up from (5,5) on a walled 10x10 board
+func entityGame(t *testing.T, walls []Point, entities ...EntityConfig) *SnakeGame {
+	level := &Level{
+		Hight:     10,
+		Width:     10,
+		Topology:  &Walled,
+		Food:      FoodConfig{Strategy: "scripted", Points: []Point{{X: 0, Y: 9}}},
+		Snake:     []Point{{X: 5, Y: 5}},
+		Direction: DirectionUp,
+		Walls:     walls,
+		Entities:  entities,
+	}
+	if err := level.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return headlessGame(level.Hight, level.Width, WithLevel(level), WithSeed(1))
+}
+
+func TestEntityContact(t *testing.T) {
+	tests := []struct {
+		name   string
+		entity EntityConfig
+		walls  []Point
+		over   bool
+		at     Point
+	}{
+		{"ball leaving the cell the head steps on", EntityConfig{Type: "ball", Position: Point{X: 5, Y: 4}, Velocity: Point{X: 1, Y: -1}}, nil, true, Point{X: 6, Y: 3}},
+		{"ball swapping cells with the head", EntityConfig{Type: "ball", Position: Point{X: 5, Y: 4}, Velocity: Point{X: 0, Y: 1}}, nil, true, Point{X: 5, Y: 5}},
+		{"ball moving onto the head", EntityConfig{Type: "ball", Position: Point{X: 6, Y: 5}, Velocity: Point{X: -1, Y: -1}}, nil, true, Point{X: 5, Y: 4}},
+		{"ball passing by", EntityConfig{Type: "ball", Position: Point{X: 7, Y: 5}, Velocity: Point{X: 1, Y: 1}}, nil, false, Point{X: 8, Y: 6}},
+		{"ball bouncing off a wall", EntityConfig{Type: "ball", Position: Point{X: 2, Y: 2}, Velocity: Point{X: 1, Y: 1}}, []Point{{X: 3, Y: 2}}, false, Point{X: 1, Y: 3}},
+		{"ball bouncing off a wall corner", EntityConfig{Type: "ball", Position: Point{X: 2, Y: 2}, Velocity: Point{X: 1, Y: 1}}, []Point{{X: 3, Y: 3}}, false, Point{X: 1, Y: 1}},
+		{"chaser going around a wall", EntityConfig{Type: "chaser", Position: Point{X: 2, Y: 5}, Every: 1}, []Point{{X: 3, Y: 5}}, false, Point{X: 2, Y: 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := entityGame(t, test.walls, test.entity)
+			game.step()
+			if game.gameOver != test.over {
+				t.Errorf("game over %v, want %v", game.gameOver, test.over)
+			}
+			if at := game.entities[0].Cells()[0]; at != test.at {
+				t.Errorf("entity at %v, want %v", at, test.at)
+			}
+		})
+	}
+}
+
+// Food and power-ups never spawn under an entity
+func TestRandomFreeAvoidsEntities(t *testing.T) {
+	var balls []EntityConfig
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x += 2 {
+			if y != 5 && y != 9 {
+				balls = append(balls, EntityConfig{Type: "ball", Position: Point{X: x + y%2, Y: y}, Velocity: Point{X: 1, Y: 1}})
+			}
+		}
+	}
+	game := entityGame(t, nil, balls...)
+	for i := 0; i < 500; i++ {
+		p, ok := arena{game}.RandomFree()
+		if !ok || game.underEntity(p) || game.board.get(p) != CellEmpty {
+			t.Fatalf("picked %v, ok %v", p, ok)
+		}
+	}
+}
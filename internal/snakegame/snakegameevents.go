@@ -0,0 +1,82 @@
+package snakegame
+
+import (
+	"time"
+)
+
+type EventKind int8
+
+const (
+	// End of every tick, after the other events of the tick
+	EventTick EventKind = iota
+	// The player changed the heading
+	EventTurn
+	// The head reached the food
+	EventEat
+	// The snake died, it may still respawn
+	EventCrash
+	// No room left for food
+	EventCleared
+	// The head reached the level exit
+	EventExit
+	// Every food of a finite spawner was eaten
+	EventAllEaten
+	// Every rival crashed before the snake
+	EventOutlived
+)
+
+// Something that happened in the game with the state right after it
+type Event struct {
+	Kind    EventKind
+	Tick    int
+	Elapsed time.Duration
+	Score   int
+	Length  int
+	// Heading after and before a turn
+	Direction Direction
+	From      Direction
+}
+
+// Receives the game events in the order they happen, called on the game loop
+type EventHandler func(event Event)
+
+// Send the game events to the handler, e.g. to unlock achievements
+func WithEventHandler(handler EventHandler) Option {
+	return func(game *SnakeGame) {
+		game.eventHandlers = append(game.eventHandlers, handler)
+	}
+}
+
+// Check if the turn is counterclockwise
+func (e Event) TurnsLeft() bool {
+	from, to := directionDeltas[e.From], directionDeltas[e.Direction]
+	return e.Kind == EventTurn && from.X*to.Y-from.Y*to.X < 0
+}
+
+// Fill in the game state and pass the event to the handlers, ghost games stay silent
+func (game *SnakeGame) emit(event Event) {
+	if len(game.eventHandlers) == 0 || game.ghostRun {
+		return
+	}
+	event.Tick = game.tick
+	event.Elapsed = game.elapsed
+	event.Score = game.score
+	event.Length = game.snake.len()
+	for _, handler := range game.eventHandlers {
+		handler(event)
+	}
+}
+
+// End the game as won by the event
+func (game *SnakeGame) win(kind EventKind) {
+	game.won, game.wonBy = true, kind
+	game.emit(Event{Kind: kind})
+}
+
+// Events closing a tick
+func (game *SnakeGame) emitTickEnd() {
+	if game.gameOver {
+		game.emit(Event{Kind: EventCrash})
+	}
+	game.emit(Event{Kind: EventTick})
+}
@@ -0,0 +1,373 @@
+package snakegame
+
+import (
+	"fmt"
+)
+
+// Food placement strategy, must return a free cell or false to spawn nothing for now
+type FoodSpawner interface {
+	Spawn(arena Arena) (Point, bool)
+}
+
+// Food placement that runs out for good, e.g. a script of cells
+type FiniteSpawner interface {
+	FoodSpawner
+	// Check if no more food will ever be spawned
+	Exhausted(arena Arena) bool
+}
+
+// Read-only view of the game given to food spawners
+type Arena interface {
+	Size() (hight int, width int)
+	IsFree(p Point) bool
+	FreeCount() int
+	// Uniformly chosen free cell, false if there is none
+	RandomFree() (Point, bool)
+	Heads() []Point
+	// Number of food items spawned so far
+	Spawned() int
+	// Deterministic random value in [0, n)
+	Intn(n int) int
+}
+
+// Serializable food placement settings, e.g. as a part of a level
+type FoodConfig struct {
+	Strategy    string  `json:"strategy"`
+	MinDistance int     `json:"minDistance,omitempty"`
+	Points      []Point `json:"points,omitempty"`
+	Loop        bool    `json:"loop,omitempty"`
+}
+
+// Build the spawner described by the config, empty strategy means uniform
+func (c FoodConfig) Spawner() (FoodSpawner, error) {
+	switch c.Strategy {
+	case "", "uniform":
+		return UniformSpawner{}, nil
+	case "distance":
+		return DistanceSpawner{MinDistance: c.MinDistance}, nil
+	case "edges":
+		return EdgeSpawner{}, nil
+	case "scripted":
+		if len(c.Points) == 0 {
+			return nil, fmt.Errorf("scripted food strategy needs points")
+		}
+		return ScriptedSpawner{Points: c.Points, Loop: c.Loop}, nil
+	case "fair":
+		return FairSpawner{}, nil
+	case "none":
+		return NoFood{}, nil
+	default:
+		return nil, fmt.Errorf("unknown food strategy %q", c.Strategy)
+	}
+}
+
+// Describe a built-in spawner for saving
+func foodConfig(spawner FoodSpawner) (FoodConfig, error) {
+	switch s := spawner.(type) {
+	case UniformSpawner:
+		return FoodConfig{Strategy: "uniform"}, nil
+	case DistanceSpawner:
+		return FoodConfig{Strategy: "distance", MinDistance: s.MinDistance}, nil
+	case EdgeSpawner:
+		return FoodConfig{Strategy: "edges"}, nil
+	case ScriptedSpawner:
+		return FoodConfig{Strategy: "scripted", Points: s.Points, Loop: s.Loop}, nil
+	case FairSpawner:
+		return FoodConfig{Strategy: "fair"}, nil
+	case NoFood:
+		return FoodConfig{Strategy: "none"}, nil
+	default:
+		return FoodConfig{}, fmt.Errorf("food spawner %T can't be saved", spawner)
+	}
+}
+
+// Use the food spawner instead of the uniform one
+func WithFoodSpawner(spawner FoodSpawner) Option {
+	return func(game *SnakeGame) {
+		game.foodSpawner = spawner
+	}
+}
+
+// Number of random probes before picking from the free cells explicitly
+const foodProbes = 16
+
+// Any free cell with equal probability
+type UniformSpawner struct{}
+
+func (UniformSpawner) Spawn(arena Arena) (Point, bool) {
+	return pickFree(arena, func(p Point) bool { return true })
+}
+
+// Free cell at least MinDistance steps away from every head
+type DistanceSpawner struct {
+	MinDistance int
+}
+
+func (s DistanceSpawner) Spawn(arena Arena) (Point, bool) {
+	heads := arena.Heads()
+	p, ok := pickFree(arena, func(p Point) bool {
+		for _, head := range heads {
+			if distance(p, head) < s.MinDistance {
+				return false
+			}
+		}
+		return true
+	})
+	if !ok {
+		return UniformSpawner{}.Spawn(arena)
+	}
+	return p, true
+}
+
+// Free cell preferring the ones close to the board edges
+type EdgeSpawner struct{}
+
+func (EdgeSpawner) Spawn(arena Arena) (Point, bool) {
+	hight, width := arena.Size()
+	for i := 0; i < foodProbes*foodProbes; i++ {
+		p, ok := arena.RandomFree()
+		if !ok {
+			return p, false
+		}
+
+		// Accept with probability 1/(1+d) where d is the distance to the closest edge
+		d := min(p.X, p.Y, width-1-p.X, hight-1-p.Y)
+		if arena.Intn(d+1) == 0 {
+			return p, true
+		}
+	}
+	return UniformSpawner{}.Spawn(arena)
+}
+
+// Fixed sequence of cells, e.g. for tutorials. Occupied cells are replaced by
+// a uniform pick, once the sequence is over there is no more food unless looped
+type ScriptedSpawner struct {
+	Points []Point
+	Loop   bool
+}
+
+func (s ScriptedSpawner) Spawn(arena Arena) (Point, bool) {
+	n := arena.Spawned()
+	if s.Exhausted(arena) {
+		return Point{}, false
+	}
+
+	p := s.Points[n%len(s.Points)]
+	if !arena.IsFree(p) {
+		return UniformSpawner{}.Spawn(arena)
+	}
+	return p, true
+}
+
+func (s ScriptedSpawner) Exhausted(arena Arena) bool {
+	return arena.Spawned() >= len(s.Points) && !s.Loop
+}
+
+// No food at all, e.g. for levels won by reaching the exit
+type NoFood struct{}
+
+func (NoFood) Spawn(arena Arena) (Point, bool) {
+	return Point{}, false
+}
+
+// Free cell with the smallest spread of distances to all heads, for multiplayer
+type FairSpawner struct{}
+
+func (FairSpawner) Spawn(arena Arena) (Point, bool) {
+	heads := arena.Heads()
+	best, bestSpread := Point{}, -1
+	for i := 0; i < foodProbes; i++ {
+		p, ok := arena.RandomFree()
+		if !ok {
+			break
+		}
+
+		nearest, farthest := -1, 0
+		for _, head := range heads {
+			d := distance(p, head)
+			if nearest < 0 || d < nearest {
+				nearest = d
+			}
+			farthest = max(farthest, d)
+		}
+		if spread := farthest - max(nearest, 0); bestSpread < 0 || spread < bestSpread {
+			best, bestSpread = p, spread
+		}
+	}
+	return best, bestSpread >= 0
+}
+
+// Uniform free cell matching the filter: a few random probes first,
+// then a single reservoir-sampling pass over all free cells growing with the board area
+func pickFree(arena Arena, accept func(p Point) bool) (Point, bool) {
+	for i := 0; i < foodProbes; i++ {
+		p, ok := arena.RandomFree()
+		if !ok {
+			return p, false
+		}
+		if accept(p) {
+			return p, true
+		}
+	}
+
+	var picked Point
+	matched := 0
+	hight, width := arena.Size()
+	for y := 0; y < hight; y++ {
+		for x := 0; x < width; x++ {
+			p := Point{X: x, Y: y}
+			if !arena.IsFree(p) || !accept(p) {
+				continue
+			}
+			matched++
+			if arena.Intn(matched) == 0 {
+				picked = p
+			}
+		}
+	}
+	return picked, matched > 0
+}
+
+// Manhattan distance between the points
+func distance(a Point, b Point) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	return max(dx, -dx) + max(dy, -dy)
+}
+
+// Arena implementation backed by the game
+type arena struct {
+	game *SnakeGame
+}
+
+func (a arena) Size() (int, int) {
+	return a.game.board.hight, a.game.board.width
+}
+
+func (a arena) IsFree(p Point) bool {
+	return a.game.board.contains(p) && a.game.board.get(p) == CellEmpty && a.game.inSafeZone(p) && !a.game.underEntity(p)
+}
+
+func (a arena) FreeCount() int {
+	if a.game.zoneLimited() {
+		free := 0
+		a.scanZone(func(p Point) { free++ })
+		return free
+	}
+	return a.game.board.free()
+}
+
+// Probing is cheap while the board is sparse, fall back to the exact pick
+// that walks the chunks and grows with the board area. The entities aren't on the board,
+// the free cells next to a covered one are tried until one of them isn't covered
+func (a arena) RandomFree() (Point, bool) {
+	b := &a.game.board
+	if a.game.zoneLimited() {
+		return a.randomFreeInZone()
+	}
+	free := b.free()
+	if free == 0 {
+		return Point{}, false
+	}
+
+	if free*2 >= b.hight*b.width {
+		for i := 0; i < foodProbes; i++ {
+			p := Point{X: a.game.random.intn(b.width), Y: a.game.random.intn(b.hight)}
+			if b.get(p) == CellEmpty && !a.game.underEntity(p) {
+				return p, true
+			}
+		}
+	}
+	n := a.game.random.intn(free)
+	for i := 0; i <= min(free-1, a.game.entityCells()); i++ {
+		if p := b.nthFree((n + i) % free); !a.game.underEntity(p) {
+			return p, true
+		}
+	}
+	return Point{}, false
+}
+
+// Uniformly chosen free cell of the shrunk safe zone
+func (a arena) randomFreeInZone() (Point, bool) {
+	margin := a.game.safeMargin()
+	hight, width := a.game.board.hight-2*margin, a.game.board.width-2*margin
+	if hight <= 0 || width <= 0 {
+		return Point{}, false
+	}
+
+	for i := 0; i < foodProbes; i++ {
+		p := Point{X: margin + a.game.random.intn(width), Y: margin + a.game.random.intn(hight)}
+		if a.IsFree(p) {
+			return p, true
+		}
+	}
+
+	var picked Point
+	matched := 0
+	a.scanZone(func(p Point) {
+		matched++
+		if a.game.random.intn(matched) == 0 {
+			picked = p
+		}
+	})
+	return picked, matched > 0
+}
+
+// Visit every free cell of the safe zone
+func (a arena) scanZone(visit func(p Point)) {
+	b, margin := &a.game.board, a.game.safeMargin()
+	for y := margin; y < b.hight-margin; y++ {
+		for x := margin; x < b.width-margin; x++ {
+			if p := (Point{X: x, Y: y}); a.IsFree(p) {
+				visit(p)
+			}
+		}
+	}
+}
+
+func (a arena) Heads() []Point {
+	heads := []Point{a.game.snake.headPoint()}
+	for _, r := range a.game.rivals {
+		if r.alive {
+			heads = append(heads, r.snake.headPoint())
+		}
+	}
+	return heads
+}
+
+func (a arena) Spawned() int {
+	return a.game.foodSpawned
+}
+
+func (a arena) Intn(n int) int {
+	return a.game.random.intn(n)
+}
+
+// Re-generate food with the configured spawner, false if there is no room left
+func (game *SnakeGame) generateFood() bool {
+	v, ok := game.foodSpawner.Spawn(arena{game})
+	if !ok {
+		return false
+	}
+	if !game.board.contains(v) || game.board.get(v) != CellEmpty {
+		panic("Food spawner picked an occupied cell")
+	}
+
+	game.food = v
+	game.foodSpawned++
+	game.board.set(v, CellFood)
+	return true
+}
+
+// Re-generate food. Unless the level is won by reaching the exit or the safe zone shrinks,
+// the game is won once the board is full or a finite spawner is over, otherwise it goes on without food
+func (game *SnakeGame) refillFood() {
+	if game.generateFood() || game.exit != nil || game.zoneLimited() {
+		return
+	}
+	spawner, finite := game.foodSpawner.(FiniteSpawner)
+	if game.board.free() == 0 {
+		game.win(EventCleared)
+	} else if finite && spawner.Exhausted(arena{game}) {
+		game.win(EventAllEaten)
+	}
+}
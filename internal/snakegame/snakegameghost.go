@@ -0,0 +1,88 @@
+package snakegame
+
+// Recorded run replayed next to the live game, it's only drawn and never collides
+type ghost struct {
+	game *SnakeGame
+	log  InputLog
+	next int
+	over bool
+	// Viewport cells covered by the ghost on the last refresh
+	drawn []Point
+	// Set up the ghost game from the first tick
+	start func()
+}
+
+// Race against the recorded run, e.g. the personal best. The ghost plays a copy of the game
+// made with the same Init arguments and options, so they must match the recorded game
+func WithGhost(log InputLog) Option {
+	return func(game *SnakeGame) {
+		game.ghostLog = &log
+	}
+}
+
+// Start the ghost game, it doesn't autosave or record
+func newGhost(log InputLog, boardHight int, boardWidth int, borderKiller bool, options []Option) *ghost {
+	g := &ghost{log: log}
+	g.start = func() {
+		g.game, g.next, g.over = &SnakeGame{ghostRun: true}, 0, false
+		g.game.Init(boardHight, boardWidth, borderKiller, func([][]Cell, int, HUD) {}, func(chan<- Command) {}, options...)
+		g.game.commands = make(chan Command, len(log.Commands)+1)
+		g.game.autosave = nil
+		g.game.recording = false
+	}
+	g.start()
+	return g
+}
+
+// Replay the ghost game from the start up to the tick once the live game went back before it,
+// e.g. after an undo
+func (g *ghost) rewind(tick int) {
+	if g.game.tick <= tick {
+		return
+	}
+	g.start()
+	g.catchUp(tick)
+}
+
+// Step the ghost game until it reaches the tick of the live one or its log ends
+func (g *ghost) catchUp(tick int) {
+	for !g.over && g.game.tick < tick {
+		for g.next < len(g.log.Commands) && g.log.Commands[g.next].Step <= g.game.steps {
+			command := g.log.Commands[g.next]
+			g.game.commands <- Command{Kind: command.Kind, Direction: command.Direction, Player: command.Player}
+			g.next++
+		}
+		if _, over := g.game.step(); over || g.game.steps > g.log.Steps {
+			g.over = true
+		}
+	}
+}
+
+// Restore the board cells covered by the ghost in the viewport
+func (game *SnakeGame) eraseGhost() {
+	if game.ghost == nil {
+		return
+	}
+	for _, p := range game.ghost.drawn {
+		game.board.draw(p, game.board.get(p))
+	}
+	game.ghost.drawn = game.ghost.drawn[:0]
+}
+
+// Draw the ghost snake dimmed over the empty cells, it disappears once its run is over
+func (game *SnakeGame) drawGhost() {
+	if game.ghost == nil || game.ghost.over {
+		return
+	}
+	for i, p := range game.ghost.game.snake.points() {
+		if game.board.get(p) != CellEmpty {
+			continue
+		}
+		cell := CellGhostTail
+		if i == 0 {
+			cell = CellGhostHead
+		}
+		game.board.draw(p, cell)
+		game.ghost.drawn = append(game.ghost.drawn, p)
+	}
+}
@@ -0,0 +1,149 @@
+package snakegame
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Cell adjacency of the board
+type Grid interface {
+	// Directions the snake can move in
+	Directions() []Direction
+	// Neighbor cell in the direction, may lie outside of the board
+	Neighbor(p Point, d Direction) Point
+	// Map a requested direction to a valid one given the current heading, false if there is none
+	Resolve(requested Direction, current Direction) (Direction, bool)
+}
+
+// Coordinates change for a single step in every square grid direction
+var directionDeltas = map[Direction]Point{
+	DirectionUp:        {X: 0, Y: -1},
+	DirectionRight:     {X: 1, Y: 0},
+	DirectionDown:      {X: 0, Y: 1},
+	DirectionLeft:      {X: -1, Y: 0},
+	DirectionUpRight:   {X: 1, Y: -1},
+	DirectionDownRight: {X: 1, Y: 1},
+	DirectionDownLeft:  {X: -1, Y: 1},
+	DirectionUpLeft:    {X: -1, Y: -1},
+}
+
+// Square cells moving in 4 directions, or in 8 with the diagonals
+type SquareGrid struct {
+	Diagonal bool
+}
+
+func (g SquareGrid) Directions() []Direction {
+	if g.Diagonal {
+		return []Direction{DirectionUp, DirectionUpRight, DirectionRight, DirectionDownRight, DirectionDown, DirectionDownLeft, DirectionLeft, DirectionUpLeft}
+	}
+	return []Direction{DirectionUp, DirectionRight, DirectionDown, DirectionLeft}
+}
+
+func (g SquareGrid) Neighbor(p Point, d Direction) Point {
+	delta := directionDeltas[d]
+	return Point{X: p.X + delta.X, Y: p.Y + delta.Y}
+}
+
+func (g SquareGrid) Resolve(requested Direction, current Direction) (Direction, bool) {
+	return requested, g.Diagonal || requested <= DirectionLeft
+}
+
+// Hexagonal cells in rows with every odd row shifted by half a cell to the right,
+// the snake moves left, right and diagonally
+type HexGrid struct{}
+
+func (HexGrid) Directions() []Direction {
+	return []Direction{DirectionUpRight, DirectionRight, DirectionDownRight, DirectionDownLeft, DirectionLeft, DirectionUpLeft}
+}
+
+func (HexGrid) Neighbor(p Point, d Direction) Point {
+	// Diagonal neighbors of odd rows lie half a cell further right
+	shift := p.Y & 1
+	switch d {
+	case DirectionRight:
+		return Point{X: p.X + 1, Y: p.Y}
+	case DirectionLeft:
+		return Point{X: p.X - 1, Y: p.Y}
+	case DirectionUpRight:
+		return Point{X: p.X + shift, Y: p.Y - 1}
+	case DirectionUpLeft:
+		return Point{X: p.X - 1 + shift, Y: p.Y - 1}
+	case DirectionDownRight:
+		return Point{X: p.X + shift, Y: p.Y + 1}
+	case DirectionDownLeft:
+		return Point{X: p.X - 1 + shift, Y: p.Y + 1}
+	}
+	return p
+}
+
+// Up and down keep the current left or right heading
+func (HexGrid) Resolve(requested Direction, current Direction) (Direction, bool) {
+	left := current == DirectionLeft || current == DirectionUpLeft || current == DirectionDownLeft
+	switch requested {
+	case DirectionUp:
+		if left {
+			return DirectionUpLeft, true
+		}
+		return DirectionUpRight, true
+	case DirectionDown:
+		if left {
+			return DirectionDownLeft, true
+		}
+		return DirectionDownRight, true
+	}
+	return requested, true
+}
+
+// Parse a grid name: square, diagonal or hex
+func ParseGrid(name string) (Grid, error) {
+	switch name {
+	case "", "square":
+		return SquareGrid{}, nil
+	case "diagonal":
+		return SquareGrid{Diagonal: true}, nil
+	case "hex":
+		return HexGrid{}, nil
+	default:
+		return nil, fmt.Errorf("unknown grid %q", name)
+	}
+}
+
+// Name of a built-in grid accepted by ParseGrid
+func gridName(grid Grid) (string, error) {
+	switch g := grid.(type) {
+	case SquareGrid:
+		if g.Diagonal {
+			return "diagonal", nil
+		}
+		return "square", nil
+	case HexGrid:
+		return "hex", nil
+	default:
+		return "", fmt.Errorf("grid %T can't be saved", grid)
+	}
+}
+
+// Play on the grid instead of the square one
+func WithGrid(grid Grid) Option {
+	return func(game *SnakeGame) {
+		game.grid = grid
+	}
+}
+
+// Check that the board edges join the grid cells alike both ways: the shifted hex rows
+// only line up across wrapped top and bottom edges of an even hight and never across mirrored edges
+func checkGrid(grid Grid, topology Topology, hight int) error {
+	edges, ok := topology.(EdgeTopology)
+	if _, hex := grid.(HexGrid); !hex || !ok {
+		return nil
+	}
+	for _, rule := range []EdgeRule{edges.Top, edges.Right, edges.Bottom, edges.Left} {
+		if rule == EdgeMirror {
+			return errors.New("hex grid can't have mirrored edges")
+		}
+	}
+	if (edges.Top == EdgeWrap || edges.Bottom == EdgeWrap) && hight%2 == 1 {
+		return errors.New("hex grid needs an even hight to wrap the top and bottom edges")
+	}
+	return nil
+}
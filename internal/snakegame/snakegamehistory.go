@@ -0,0 +1,134 @@
+package snakegame
+
+import (
+	"time"
+)
+
+// Compact copy of the mutable game state
+type snapshot struct {
+	snake         []Point
+	rivals        []rival
+	food          Point
+	noFood        bool
+	powerUp       *powerUp
+	effects       [powerUpKinds]int
+	entities      []Entity
+	foodSpawned   int
+	moveDirection Direction
+	growth        int
+	eaten         int
+	gameOver      bool
+	score         int
+	tick          int
+	collapsed     int
+	nextCollapse  int
+	elapsed       time.Duration
+	tickInterval  time.Duration
+	random        random
+}
+
+// Bounded ring of the latest snapshots
+type history struct {
+	items []snapshot
+	start int
+	size  int
+}
+
+// History initialization, zero capacity disables recording
+func (h *history) init(capacity int) {
+	h.items = make([]snapshot, capacity)
+	h.start = 0
+	h.size = 0
+}
+
+// Store snapshot dropping the oldest one when full
+func (h *history) push(s snapshot) {
+	if len(h.items) == 0 {
+		return
+	}
+	if h.size == len(h.items) {
+		h.start = (h.start + 1) % len(h.items)
+		h.size--
+	}
+	h.items[(h.start+h.size)%len(h.items)] = s
+	h.size++
+}
+
+// Take the latest snapshot
+func (h *history) pop() snapshot {
+	h.size--
+	return h.items[(h.start+h.size)%len(h.items)]
+}
+
+// Check if there is nothing to rewind
+func (h *history) empty() bool {
+	return h.size == 0
+}
+
+// Copy the current state
+func (game *SnakeGame) snapshot() snapshot {
+	return snapshot{
+		snake:         game.snake.points(),
+		rivals:        cloneRivals(game.rivals),
+		food:          game.food,
+		noFood:        game.board.get(game.food) != CellFood,
+		powerUp:       game.powerUp,
+		effects:       game.effects,
+		entities:      cloneEntities(game.entities),
+		foodSpawned:   game.foodSpawned,
+		moveDirection: game.moveDirection,
+		growth:        game.growth,
+		eaten:         game.eaten,
+		gameOver:      game.gameOver,
+		score:         game.score,
+		tick:          game.tick,
+		collapsed:     game.collapsed,
+		nextCollapse:  game.nextCollapse,
+		elapsed:       game.elapsed,
+		tickInterval:  game.tickInterval,
+		random:        game.random,
+	}
+}
+
+// Bring the state back to the snapshot
+func (game *SnakeGame) restore(s snapshot) {
+	game.board.clean()
+	game.collapsed = s.collapsed
+	game.nextCollapse = s.nextCollapse
+	game.placeLayout()
+	game.overlaps = make(map[Point]int)
+	game.placeSnake(s.snake)
+	game.rivals = cloneRivals(s.rivals)
+	for i := range game.rivals {
+		game.placeRival(i, game.rivals[i].snake.points())
+	}
+	game.food = s.food
+	if !s.noFood {
+		game.board.set(s.food, CellFood)
+	}
+	game.powerUp = s.powerUp
+	if s.powerUp != nil {
+		game.board.set(s.powerUp.At, powerUpCells[s.powerUp.Kind])
+	}
+	game.effects = s.effects
+	game.foodSpawned = s.foodSpawned
+	game.entities = s.entities
+	game.moveDirection = s.moveDirection
+	game.growth = s.growth
+	game.eaten = s.eaten
+	game.gameOver = s.gameOver
+	game.score = s.score
+	game.tick = s.tick
+	game.elapsed = s.elapsed
+	game.tickInterval = s.tickInterval
+	game.random = s.random
+}
+
+// Deep copy of the entities
+func cloneEntities(entities []Entity) []Entity {
+	clones := make([]Entity, len(entities))
+	for i, entity := range entities {
+		clones[i] = entity.Clone()
+	}
+	return clones
+}
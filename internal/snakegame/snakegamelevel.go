@@ -0,0 +1,267 @@
+package snakegame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Board layout and settings loaded from a level file
+type Level struct {
+	Name     string        `json:"name,omitempty"`
+	Hight    int           `json:"hight"`
+	Width    int           `json:"width"`
+	Topology *EdgeTopology `json:"topology,omitempty"`
+	// Grid the snake is laid out on, see ParseGrid. Square levels fit every grid
+	Grid     string         `json:"grid,omitempty"`
+	Food     FoodConfig     `json:"food"`
+	Portals  []PortalPair   `json:"portals,omitempty"`
+	Entities []EntityConfig `json:"entities,omitempty"`
+	Walls    []Point        `json:"walls,omitempty"`
+	// Starting snake, head first, and its direction. Empty means a single segment in the center
+	Snake     []Point   `json:"snake,omitempty"`
+	Direction Direction `json:"direction,omitempty"`
+	// Reaching the exit wins the game instead of clearing the board
+	Exit *Point `json:"exit,omitempty"`
+	// Starting cells of every player in multiplayer games, the first one is the snake's
+	// and matches its head if the level places the snake, the rivals take the others
+	Spawns []Spawn `json:"spawns,omitempty"`
+}
+
+// Starting cell and direction of a player
+type Spawn struct {
+	At        Point     `json:"at"`
+	Direction Direction `json:"direction"`
+}
+
+// Two linked portal cells, entering one of them exits from the other
+type PortalPair struct {
+	A Point `json:"a"`
+	B Point `json:"b"`
+}
+
+// Read and validate a level
+func LoadLevel(r io.Reader) (*Level, error) {
+	var level Level
+	if err := json.NewDecoder(r).Decode(&level); err != nil {
+		return nil, err
+	}
+	if err := level.Validate(); err != nil {
+		return nil, err
+	}
+	return &level, nil
+}
+
+// Write the level
+func (level *Level) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(level)
+}
+
+// Check that the level fits the board and its cells don't overlap
+func (level *Level) Validate() error {
+	if level.Hight > MaxBoardSize || level.Hight <= 0 || level.Width > MaxBoardSize || level.Width <= 0 {
+		return errors.New("level size is out of range")
+	}
+	if _, err := level.Food.Spawner(); err != nil {
+		return err
+	}
+	grid, err := ParseGrid(level.Grid)
+	if err != nil {
+		return err
+	}
+	if level.Topology != nil {
+		if err := checkGrid(grid, *level.Topology, level.Hight); err != nil {
+			return err
+		}
+	}
+	if level.Food.Strategy == "none" && level.Exit == nil {
+		return errors.New("level without food needs an exit")
+	}
+	for _, config := range level.Entities {
+		if _, err := config.Entity(); err != nil {
+			return err
+		}
+		for _, p := range config.Points() {
+			if !level.contains(p) {
+				return fmt.Errorf("%s %v is out of the board", config.Type, p)
+			}
+		}
+	}
+
+	if level.Direction < DirectionUp || level.Direction > DirectionUpLeft {
+		return errors.New("level direction is invalid")
+	}
+	if _, ok := grid.Resolve(level.Direction, level.Direction); !ok {
+		return errors.New("level direction is not on the level grid")
+	}
+	for _, p := range level.Food.Points {
+		if !level.contains(p) {
+			return fmt.Errorf("food %v is out of the board", p)
+		}
+	}
+
+	used := make(map[Point]bool)
+	place := func(what string, p Point) error {
+		if !level.contains(p) {
+			return fmt.Errorf("%s %v is out of the board", what, p)
+		}
+		if used[p] {
+			return fmt.Errorf("%s %v overlaps another cell", what, p)
+		}
+		used[p] = true
+		return nil
+	}
+
+	// The snake starts at the first spawn or in the center unless placed by the level
+	snake := level.Snake
+	if len(snake) > 0 && len(level.Spawns) > 0 && level.Spawns[0].At != snake[0] {
+		return errors.New("first spawn is not the snake head")
+	}
+	if len(snake) == 0 && len(level.Spawns) > 0 {
+		snake = []Point{level.Spawns[0].At}
+	} else if len(snake) == 0 {
+		snake = []Point{{X: level.Width / 2, Y: level.Hight / 2}}
+	}
+	for i, p := range snake {
+		if err := place("snake segment", p); err != nil {
+			return err
+		}
+		if i > 0 && !neighbors(grid, snake[i-1], p) {
+			return fmt.Errorf("snake segment %v is not next to the previous one", p)
+		}
+	}
+	for _, pair := range level.Portals {
+		for _, p := range []Point{pair.A, pair.B} {
+			if err := place("portal", p); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range level.Walls {
+		if err := place("wall", p); err != nil {
+			return err
+		}
+	}
+	if level.Exit != nil {
+		if err := place("exit", *level.Exit); err != nil {
+			return err
+		}
+	}
+	for i, spawn := range level.Spawns {
+		// The first spawn is the snake head placed above
+		if i > 0 {
+			if err := place("spawn", spawn.At); err != nil {
+				return err
+			}
+		}
+		if spawn.Direction < DirectionUp || spawn.Direction > DirectionUpLeft {
+			return fmt.Errorf("spawn %v direction is invalid", spawn.At)
+		}
+		if _, ok := grid.Resolve(spawn.Direction, spawn.Direction); !ok {
+			return fmt.Errorf("spawn %v direction is not on the level grid", spawn.At)
+		}
+	}
+	return nil
+}
+
+// Check if the points are next to each other on the grid
+func neighbors(grid Grid, a Point, b Point) bool {
+	for _, d := range grid.Directions() {
+		if grid.Neighbor(a, d) == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the point lies on the level board
+func (level *Level) contains(p Point) bool {
+	return p.X >= 0 && p.X < level.Width && p.Y >= 0 && p.Y < level.Hight
+}
+
+// Play the level, its size replaces the one passed to Init
+func WithLevel(level *Level) Option {
+	return func(game *SnakeGame) {
+		game.level = level
+		game.portalPairs = append(game.portalPairs, level.Portals...)
+		game.walls = append(game.walls, level.Walls...)
+		game.exit = level.Exit
+		if level.Topology != nil {
+			game.topology = *level.Topology
+		}
+		if grid, err := ParseGrid(level.Grid); err == nil && level.Grid != "" {
+			game.grid = grid
+		}
+		if spawner, err := level.Food.Spawner(); err == nil {
+			game.foodSpawner = spawner
+		}
+		for _, config := range level.Entities {
+			if entity, err := config.Entity(); err == nil {
+				game.entities = append(game.entities, entity)
+			}
+		}
+	}
+}
+
+// Place the number of random portal pairs on free cells
+func WithRandomPortals(pairs int) Option {
+	return func(game *SnakeGame) {
+		game.randomPortals = pairs
+	}
+}
+
+// Put static cells to the board
+func (game *SnakeGame) placeLayout() {
+	game.portals = make(map[Point]Point)
+	for _, pair := range game.portalPairs {
+		if game.board.depth(pair.A) < game.collapsed || game.board.depth(pair.B) < game.collapsed {
+			continue
+		}
+		game.portals[pair.A] = pair.B
+		game.portals[pair.B] = pair.A
+		game.board.set(pair.A, CellPortal)
+		game.board.set(pair.B, CellPortal)
+	}
+	for _, p := range game.walls {
+		game.board.set(p, CellWall)
+	}
+	if game.exit != nil {
+		game.board.set(*game.exit, CellExit)
+	}
+	for ring := 0; ring < game.collapsed; ring++ {
+		for _, p := range game.board.ring(ring) {
+			game.board.set(p, CellWall)
+		}
+	}
+}
+
+// Pick random free cells for the portals
+func (game *SnakeGame) generatePortals(pairs int) {
+	for i := 0; i < pairs && game.board.free() >= 2; i++ {
+		var pair PortalPair
+		pair.A, _ = arena{game}.RandomFree()
+		game.board.set(pair.A, CellPortal)
+		pair.B, _ = arena{game}.RandomFree()
+		game.board.set(pair.B, CellPortal)
+		game.portalPairs = append(game.portalPairs, pair)
+	}
+	game.placeLayout()
+}
+
+// Pass through portals after a step, returns where the head exits
+func (game *SnakeGame) throughPortals(p Point, d Direction) (Point, Direction, bool) {
+	for hops := 0; hops <= len(game.portalPairs); hops++ {
+		exit, ok := game.portals[p]
+		if !ok {
+			return p, d, true
+		}
+		p, d, ok = game.topology.Step(game.grid, exit, d, game.board.hight, game.board.width)
+		if !ok {
+			return p, d, false
+		}
+	}
+	return p, d, false
+}
@@ -0,0 +1,173 @@
+package snakegame
+
+import (
+	"fmt"
+	"time"
+)
+
+// Game mode deciding how a run ends, modes share the game state with the rules
+type Mode interface {
+	// Name used e.g. to rank high scores separately per mode
+	Name() string
+	// Start of every tick, before the rules
+	OnTick(ctx RuleContext)
+	// Checked after every tick, true ends the run
+	Over(ctx RuleContext) bool
+	// The snake died, true respawns it instead of ending the run
+	Respawn(ctx RuleContext) bool
+	// Add the mode details to the HUD
+	HUD(ctx RuleContext, hud *HUD)
+}
+
+// Serializable mode description, e.g. for saves and flags
+type ModeConfig struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Penalty  time.Duration `json:"penalty,omitempty"`
+	Every    int           `json:"every,omitempty"`
+	Warning  int           `json:"warning,omitempty"`
+	Moves    int           `json:"moves,omitempty"`
+}
+
+// Build the mode described by the config, empty name means classic
+func (c ModeConfig) Mode() (Mode, error) {
+	switch c.Name {
+	case "", "classic":
+		return Classic{}, nil
+	case "time-attack":
+		if c.Duration <= 0 {
+			return nil, fmt.Errorf("time attack needs a duration")
+		}
+		return TimeAttack{Duration: c.Duration, Penalty: c.Penalty}, nil
+	case "survival":
+		if c.Every <= 0 {
+			return nil, fmt.Errorf("survival needs a collapse interval")
+		}
+		return Survival{Every: c.Every, Warning: c.Warning}, nil
+	case "move-limit":
+		if c.Moves <= 0 {
+			return nil, fmt.Errorf("move limit needs a number of moves")
+		}
+		return MoveLimit{Moves: c.Moves}, nil
+	default:
+		return nil, fmt.Errorf("unknown game mode %q", c.Name)
+	}
+}
+
+// Play the mode instead of the classic one
+func WithMode(mode Mode) Option {
+	return func(game *SnakeGame) {
+		game.mode = mode
+	}
+}
+
+// Run until the snake dies
+type Classic struct{}
+
+func (Classic) Name() string                  { return "classic" }
+func (Classic) OnTick(ctx RuleContext)        {}
+func (Classic) Over(ctx RuleContext) bool     { return false }
+func (Classic) Respawn(ctx RuleContext) bool  { return false }
+func (Classic) HUD(ctx RuleContext, hud *HUD) {}
+
+// Score as much as possible in the fixed game time, dying costs the penalty
+type TimeAttack struct {
+	Duration time.Duration
+	Penalty  time.Duration
+}
+
+func (m TimeAttack) Name() string {
+	return "time-attack"
+}
+
+func (m TimeAttack) OnTick(ctx RuleContext) {}
+
+func (m TimeAttack) Over(ctx RuleContext) bool {
+	return ctx.Elapsed() >= m.Duration
+}
+
+func (m TimeAttack) Respawn(ctx RuleContext) bool {
+	ctx.SetElapsed(ctx.Elapsed() + m.Penalty)
+	return ctx.Elapsed() < m.Duration
+}
+
+func (m TimeAttack) HUD(ctx RuleContext, hud *HUD) {
+	hud.TimeLeft = max(0, m.Duration-ctx.Elapsed())
+}
+
+// Border rings turn into walls every Every ticks, the next one is telegraphed
+// for the last Warning ticks and food only spawns inside of it
+type Survival struct {
+	Every   int
+	Warning int
+}
+
+func (m Survival) Name() string {
+	return "survival"
+}
+
+func (m Survival) OnTick(ctx RuleContext) {
+	if ctx.NextCollapse() <= ctx.Tick() {
+		ctx.ScheduleCollapse(ctx.Tick()+m.Every, m.Warning)
+	}
+}
+
+func (m Survival) Over(ctx RuleContext) bool     { return false }
+func (m Survival) Respawn(ctx RuleContext) bool  { return false }
+func (m Survival) HUD(ctx RuleContext, hud *HUD) {}
+
+// The run ends after the number of moves, e.g. for puzzles
+type MoveLimit struct {
+	Moves int
+}
+
+func (m MoveLimit) Name() string {
+	return "move-limit"
+}
+
+func (m MoveLimit) OnTick(ctx RuleContext) {}
+
+func (m MoveLimit) Over(ctx RuleContext) bool {
+	return ctx.Tick() >= m.Moves
+}
+
+func (m MoveLimit) Respawn(ctx RuleContext) bool {
+	return false
+}
+
+func (m MoveLimit) HUD(ctx RuleContext, hud *HUD) {
+	hud.MovesLeft = max(0, m.Moves-ctx.Tick())
+}
+
+// Describe a built-in mode for saving
+func modeConfig(mode Mode) (ModeConfig, error) {
+	switch m := mode.(type) {
+	case Classic:
+		return ModeConfig{Name: m.Name()}, nil
+	case TimeAttack:
+		return ModeConfig{Name: m.Name(), Duration: m.Duration, Penalty: m.Penalty}, nil
+	case Survival:
+		return ModeConfig{Name: m.Name(), Every: m.Every, Warning: m.Warning}, nil
+	case MoveLimit:
+		return ModeConfig{Name: m.Name(), Moves: m.Moves}, nil
+	default:
+		return ModeConfig{}, fmt.Errorf("mode %T can't be saved", mode)
+	}
+}
+
+// Replace the dead snake by a new one on a free cell, false if there is no room
+func (game *SnakeGame) respawn() bool {
+	for _, p := range game.snake.points() {
+		game.vacate(p)
+	}
+
+	start, ok := arena{game}.RandomFree()
+	if !ok {
+		return false
+	}
+	game.placeSnake([]Point{start})
+	game.moveDirection, _ = game.grid.Resolve(DirectionUp, DirectionUp)
+	game.growth = 0
+	game.gameOver = false
+	return true
+}
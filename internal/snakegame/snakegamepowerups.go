@@ -0,0 +1,168 @@
+package snakegame
+
+import (
+	"fmt"
+	"time"
+)
+
+// Collectible with a temporary effect
+type PowerUpKind int8
+
+const (
+	// Pass through the own body
+	PowerUpGhost PowerUpKind = iota
+	// Double the tick interval
+	PowerUpSlowMotion
+	// Pull the food toward the head
+	PowerUpMagnet
+	// Double the points
+	PowerUpDoubler
+
+	powerUpKinds = iota
+)
+
+var powerUpNames = []string{"ghost", "slow-motion", "magnet", "doubler"}
+
+// Board cell of every power-up kind
+var powerUpCells = []Cell{CellPowerUpGhost, CellPowerUpSlowMotion, CellPowerUpMagnet, CellPowerUpDoubler}
+
+func (k PowerUpKind) String() string {
+	if k < 0 || int(k) >= len(powerUpNames) {
+		return fmt.Sprintf("PowerUpKind(%d)", k)
+	}
+	return powerUpNames[k]
+}
+
+func (k PowerUpKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(powerUpNames) {
+		return nil, fmt.Errorf("unknown power-up %d", k)
+	}
+	return []byte(powerUpNames[k]), nil
+}
+
+func (k *PowerUpKind) UnmarshalText(text []byte) error {
+	for i, name := range powerUpNames {
+		if name == string(text) {
+			*k = PowerUpKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown power-up %q", text)
+}
+
+// Power-up spawning settings, all durations are in ticks
+type PowerUpConfig struct {
+	// Interval between spawns, zero disables power-ups
+	Every int `json:"every"`
+	// How long an uncollected power-up stays, zero means until collected
+	Lifetime int `json:"lifetime,omitempty"`
+	// How long a collected effect lasts
+	Duration int `json:"duration"`
+}
+
+// Power-up lying on the board
+type powerUp struct {
+	Kind    PowerUpKind `json:"kind"`
+	At      Point       `json:"at"`
+	Expires int         `json:"expires,omitempty"`
+}
+
+// Spawn a random power-up on a free cell every config.Every ticks
+func WithPowerUps(config PowerUpConfig) Option {
+	return func(game *SnakeGame) {
+		game.powerUps = config
+	}
+}
+
+// Remove the expired power-up and spawn a new one when it's time
+func (game *SnakeGame) tickPowerUps() {
+	if p := game.powerUp; p != nil && game.powerUps.Lifetime > 0 && game.tick >= p.Expires {
+		game.board.set(p.At, CellEmpty)
+		game.powerUp = nil
+	}
+
+	if game.powerUp != nil || game.powerUps.Every <= 0 || game.tick%game.powerUps.Every != 0 {
+		return
+	}
+	at, ok := arena{game}.RandomFree()
+	if !ok {
+		return
+	}
+	game.powerUp = &powerUp{Kind: PowerUpKind(game.random.intn(powerUpKinds)), At: at, Expires: game.tick + game.powerUps.Lifetime}
+	game.board.set(at, powerUpCells[game.powerUp.Kind])
+}
+
+// Activate the power-up under the head, its cell is already taken by the head
+func (game *SnakeGame) collectPowerUp(head Point) {
+	if !game.isPowerUp(head) {
+		return
+	}
+	game.effects[game.powerUp.Kind] = game.tick + game.powerUps.Duration
+	game.powerUp = nil
+}
+
+// Check if the power-up lies on the point
+func (game *SnakeGame) isPowerUp(p Point) bool {
+	return game.powerUp != nil && game.powerUp.At == p
+}
+
+// Check if the effect lasts through the current tick
+func (game *SnakeGame) effectActive(kind PowerUpKind) bool {
+	until := game.effects[kind]
+	return until > 0 && game.tick <= until
+}
+
+// Move the food one step toward the head if the cell on the way is empty
+func (game *SnakeGame) pullFood() {
+	food, head := game.food, game.snake.headPoint()
+	if game.board.get(food) != CellFood {
+		return
+	}
+
+	dx, dy := head.X-food.X, head.Y-food.Y
+	steps := []Point{{X: sign(dx)}, {Y: sign(dy)}}
+	if max(dy, -dy) > max(dx, -dx) {
+		steps[0], steps[1] = steps[1], steps[0]
+	}
+	for _, step := range steps {
+		next := Point{X: food.X + step.X, Y: food.Y + step.Y}
+		if next == food || !game.board.contains(next) || game.board.get(next) != CellEmpty {
+			continue
+		}
+		game.board.set(food, CellEmpty)
+		game.board.set(next, CellFood)
+		game.food = next
+		return
+	}
+}
+
+// Time to wait before the next tick, longer while slowed down
+func (game *SnakeGame) interval() time.Duration {
+	if game.effectActive(PowerUpSlowMotion) {
+		return game.tickInterval * 2
+	}
+	return game.tickInterval
+}
+
+// Factor applied to the points scored
+func (game *SnakeGame) scoreMultiplier() int {
+	if game.effectActive(PowerUpDoubler) {
+		return 2
+	}
+	return 1
+}
+
+// Effects still running with the number of ticks left
+func (game *SnakeGame) activeEffects() []ActiveEffect {
+	var effects []ActiveEffect
+	for kind, until := range game.effects {
+		if remaining := until - game.tick; until > 0 && remaining > 0 {
+			effects = append(effects, ActiveEffect{Kind: PowerUpKind(kind), Remaining: remaining})
+		}
+	}
+	return effects
+}
+
+func sign(v int) int {
+	return max(-1, min(1, v))
+}
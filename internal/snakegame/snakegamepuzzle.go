@@ -0,0 +1,234 @@
+package snakegame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// What has to be done to solve a puzzle
+type PuzzleGoal string
+
+const (
+	// Eat every food of the level script
+	GoalEatAll PuzzleGoal = "eat-all"
+	// Move the head onto the level exit
+	GoalReachExit PuzzleGoal = "reach-exit"
+)
+
+// Seed of every puzzle game, food falling back to a random cell stays the same
+const puzzleSeed = 1
+
+// Largest number of positions the solver explores
+const maxSolverStates = 1 << 20
+
+// Hand-made position to solve within the move limit, played turn-based
+type Puzzle struct {
+	Level
+	Goal     PuzzleGoal `json:"goal"`
+	MaxMoves int        `json:"maxMoves"`
+	// Known solution as direction letters, e.g. "UURDL"
+	Solution string `json:"solution,omitempty"`
+}
+
+// Ordered set of puzzles shipped together
+type PuzzlePack struct {
+	Name    string   `json:"name"`
+	Puzzles []Puzzle `json:"puzzles"`
+}
+
+// Direction letters of the puzzle solutions
+var moveLetters = map[Direction]byte{
+	DirectionUp:    'U',
+	DirectionRight: 'R',
+	DirectionDown:  'D',
+	DirectionLeft:  'L',
+}
+
+// Read and validate a puzzle pack
+func LoadPuzzlePack(r io.Reader) (*PuzzlePack, error) {
+	var pack PuzzlePack
+	if err := json.NewDecoder(r).Decode(&pack); err != nil {
+		return nil, err
+	}
+	if len(pack.Puzzles) == 0 {
+		return nil, errors.New("puzzle pack is empty")
+	}
+	for i := range pack.Puzzles {
+		if err := pack.Puzzles[i].Validate(); err != nil {
+			return nil, fmt.Errorf("puzzle %d: %w", i+1, err)
+		}
+	}
+	return &pack, nil
+}
+
+// Write the puzzle pack
+func (pack *PuzzlePack) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(pack)
+}
+
+// Check the level, the goal and the solution format
+func (p *Puzzle) Validate() error {
+	if err := p.Level.Validate(); err != nil {
+		return err
+	}
+	// Puzzles without a topology of their own are played on the default torus
+	if grid, _ := ParseGrid(p.Grid); p.Topology == nil {
+		if err := checkGrid(grid, Torus, p.Hight); err != nil {
+			return err
+		}
+	}
+	if p.MaxMoves <= 0 {
+		return errors.New("puzzle needs a move limit")
+	}
+	switch p.Goal {
+	case GoalEatAll:
+		if p.Food.Strategy != "scripted" || p.Food.Loop {
+			return errors.New("eat-all puzzle needs scripted food without a loop")
+		}
+		if p.Exit != nil {
+			return errors.New("eat-all puzzle can't have an exit")
+		}
+	case GoalReachExit:
+		if p.Exit == nil {
+			return errors.New("reach-exit puzzle needs an exit")
+		}
+	default:
+		return fmt.Errorf("unknown puzzle goal %q", p.Goal)
+	}
+	_, err := ParseMoves(p.Solution)
+	return err
+}
+
+// Options to play the puzzle, the board size comes from its level
+func (p *Puzzle) Options() []Option {
+	return []Option{
+		WithLevel(&p.Level),
+		WithMode(MoveLimit{Moves: p.MaxMoves}),
+		WithTurnBased(p.MaxMoves),
+		WithSeed(puzzleSeed),
+	}
+}
+
+// Re-play the solution on the engine, nil if every move counts and it solves the puzzle
+func (p *Puzzle) Verify(solution string) error {
+	moves, err := ParseMoves(solution)
+	if err != nil {
+		return err
+	}
+	if len(moves) > p.MaxMoves {
+		return fmt.Errorf("solution takes %d moves, the limit is %d", len(moves), p.MaxMoves)
+	}
+
+	log := InputLog{Steps: len(moves)}
+	for i, d := range moves {
+		log.Commands = append(log.Commands, LoggedCommand{Step: i, Kind: CommandTurn, Direction: d})
+	}
+	game := p.newGame()
+	switch result := game.Replay(log); {
+	case result.Outcome == OutcomeWon && game.tick == len(moves):
+		return nil
+	case result.Outcome == OutcomeWon:
+		return fmt.Errorf("solution has %d moves turning back or past the goal", len(moves)-game.tick)
+	case game.gameOver:
+		return fmt.Errorf("snake crashes on move %d", game.tick)
+	default:
+		return fmt.Errorf("puzzle is not solved after %d moves", game.tick)
+	}
+}
+
+// Find the shortest solution by a breadth-first search over the engine states
+func (p *Puzzle) Solve() (string, error) {
+	game := p.newGame()
+	if game.won {
+		return "", nil
+	}
+
+	type node struct {
+		state snapshot
+		moves string
+	}
+	queue := []node{{state: game.snapshot()}}
+	seen := map[string]bool{positionKey(queue[0].state): true}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if len(n.moves) >= p.MaxMoves {
+			continue
+		}
+
+		// Every letter a solution is written with, resolved on the grid like the live input
+		for _, requested := range []Direction{DirectionUp, DirectionRight, DirectionDown, DirectionLeft} {
+			d, ok := game.grid.Resolve(requested, n.state.moveDirection)
+			if !ok || d == oppositeDirections[n.state.moveDirection] {
+				continue
+			}
+
+			game.restore(n.state)
+			game.entities = cloneEntities(n.state.entities)
+			game.won = false
+			game.moveDirection = d
+			game.calculateIteration()
+			moves := n.moves + string(moveLetters[requested])
+			if game.won {
+				return moves, nil
+			}
+			if game.gameOver {
+				continue
+			}
+
+			state := game.snapshot()
+			key := positionKey(state)
+			if seen[key] {
+				continue
+			}
+			if len(seen) >= maxSolverStates {
+				return "", errors.New("puzzle is too large to solve")
+			}
+			seen[key] = true
+			queue = append(queue, node{state: state, moves: moves})
+		}
+	}
+	return "", fmt.Errorf("puzzle can't be solved in %d moves", p.MaxMoves)
+}
+
+// Parse direction letters of a solution
+func ParseMoves(s string) ([]Direction, error) {
+	moves := make([]Direction, 0, len(s))
+	for _, r := range strings.ToUpper(s) {
+		found := false
+		for d, letter := range moveLetters {
+			if rune(letter) == r {
+				moves = append(moves, d)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown move %q, expected U, R, D or L", r)
+		}
+	}
+	return moves, nil
+}
+
+// Headless game of the puzzle
+func (p *Puzzle) newGame() *SnakeGame {
+	game := &SnakeGame{}
+	game.Init(p.Hight, p.Width, false, func([][]Cell, int, HUD) {}, func(chan<- Command) {}, p.Options()...)
+	return game
+}
+
+// Everything deciding how the game goes on from the snapshot,
+// the tick only matters for the entities moving on their own
+func positionKey(s snapshot) string {
+	var key strings.Builder
+	fmt.Fprint(&key, s.snake, s.food, s.noFood, s.foodSpawned, s.growth, s.moveDirection, s.random.state)
+	for _, entity := range s.entities {
+		fmt.Fprintf(&key, " %d %+v", s.tick, entity)
+	}
+	return key.String()
+}
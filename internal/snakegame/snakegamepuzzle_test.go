@@ -0,0 +1,64 @@
+package snakegame
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Every shipped puzzle is solvable and the found solution passes the verification
+func TestPuzzlePacks(t *testing.T) {
+	paths, err := filepath.Glob("../../puzzles/*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no puzzle packs found: %v", err)
+	}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pack, err := LoadPuzzlePack(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		for i := range pack.Puzzles {
+			checkPuzzle(t, &pack.Puzzles[i])
+		}
+	}
+}
+
+// Up and down turn diagonally on the hex grid, the solver tries them like the live input
+func TestSolveHex(t *testing.T) {
+	exit := Point{X: 2, Y: 3}
+	checkPuzzle(t, &Puzzle{
+		Level: Level{
+			Name:      "hex",
+			Hight:     4,
+			Width:     4,
+			Topology:  &Walled,
+			Grid:      "hex",
+			Food:      FoodConfig{Strategy: "none"},
+			Snake:     []Point{{X: 0, Y: 0}},
+			Direction: DirectionRight,
+			Exit:      &exit,
+		},
+		Goal:     GoalReachExit,
+		MaxMoves: 4,
+	})
+}
+
+// Solve the puzzle and verify the solution
+func checkPuzzle(t *testing.T, puzzle *Puzzle) {
+	t.Helper()
+	if err := puzzle.Validate(); err != nil {
+		t.Fatalf("%s: %v", puzzle.Name, err)
+	}
+	solution, err := puzzle.Solve()
+	if err != nil {
+		t.Fatalf("%s: %v", puzzle.Name, err)
+	}
+	if err := puzzle.Verify(solution); err != nil {
+		t.Errorf("%s: solution %q fails: %v", puzzle.Name, solution, err)
+	}
+}
@@ -0,0 +1,28 @@
+package snakegame
+
+// Deterministic random generator (SplitMix64) with serializable state
+type random struct {
+	state uint64
+}
+
+// Reset generator state from the seed
+func (r *random) seed(seed int64) {
+	r.state = uint64(seed)
+}
+
+// Next pseudo-random 64-bit value
+func (r *random) next() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Pseudo-random value in [0, n)
+func (r *random) intn(n int) int {
+	if n <= 0 {
+		panic("Invalid argument to intn")
+	}
+	return int(r.next() % uint64(n))
+}
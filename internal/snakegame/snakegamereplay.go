@@ -0,0 +1,50 @@
+package snakegame
+
+// Commands consumed by the game with the main loop step they were applied at
+type InputLog struct {
+	Commands []LoggedCommand `json:"commands"`
+	// Number of main loop steps played
+	Steps int `json:"steps"`
+}
+
+// Command recorded at the main loop step
+type LoggedCommand struct {
+	Step      int         `json:"step"`
+	Kind      CommandKind `json:"kind"`
+	Direction Direction   `json:"direction,omitempty"`
+	Player    int         `json:"player,omitempty"`
+}
+
+// Record every consumed command to replay the game later
+func WithRecording() Option {
+	return func(game *SnakeGame) {
+		game.recording = true
+	}
+}
+
+// Commands recorded so far
+func (game *SnakeGame) InputLog() InputLog {
+	log := game.inputLog
+	log.Steps = game.steps
+	return log
+}
+
+// Re-simulate the game from the input log without displaying and waiting,
+// the game must be initialized with the same seed and options as the recorded one.
+// A log ending before the game is over gives OutcomeQuit
+func (game *SnakeGame) Replay(log InputLog) Result {
+	game.commands = make(chan Command, len(log.Commands)+1)
+	next := 0
+	for {
+		for next < len(log.Commands) && log.Commands[next].Step <= game.steps {
+			game.commands <- Command{Kind: log.Commands[next].Kind, Direction: log.Commands[next].Direction, Player: log.Commands[next].Player}
+			next++
+		}
+		if result, over := game.step(); over {
+			return result
+		}
+		if game.steps > log.Steps {
+			return Result{Score: game.score, Outcome: OutcomeQuit}
+		}
+	}
+}
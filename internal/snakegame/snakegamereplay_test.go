@@ -0,0 +1,44 @@
+package snakegame
+
+import (
+	"testing"
+	"time"
+)
+
+// Game settings covering the engine features a replay has to reproduce
+var replayCases = []struct {
+	name    string
+	hight   int
+	width   int
+	options []Option
+}{
+	{"classic", 10, 10, nil},
+	{"walled diagonal", 12, 16, []Option{WithTopology(Walled), WithGrid(SquareGrid{Diagonal: true}), WithFoodSpawner(DistanceSpawner{MinDistance: 4})}},
+	{"klein portals balls", 15, 15, []Option{WithTopology(KleinBottle), WithRandomPortals(2), WithRandomEnemies(2, 1), WithPowerUps(PowerUpConfig{Every: 5, Lifetime: 10, Duration: 5})}},
+	{"hex", 12, 12, []Option{WithGrid(HexGrid{}), WithRules(GrowOnEat{Segments: 2}, ScoreOnEat{Points: 1})}},
+	{"time attack", 10, 12, []Option{WithTopology(Bouncing), WithMode(TimeAttack{Duration: 20 * time.Second, Penalty: 5 * time.Second})}},
+	{"light cycles", 14, 20, []Option{WithTopology(Walled), WithRules(Trail{}), WithFoodSpawner(NoFood{}), WithRivals(1, SpaceBot{Depth: 16})}},
+}
+
+// Replaying the recorded input with the same seed gives the same game
+func TestReplayReproducesTheGame(t *testing.T) {
+	for _, c := range replayCases {
+		t.Run(c.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				options := append([]Option{WithSeed(seed)}, c.options...)
+				recorded := headlessGame(c.hight, c.width, append(options, WithRecording())...)
+				want := playScripted(recorded, 300, uint64(seed))
+
+				replayed := headlessGame(c.hight, c.width, options...)
+				got := replayed.Replay(recorded.InputLog())
+				if got != want {
+					t.Errorf("seed %d: replayed %+v, recorded %+v", seed, got, want)
+				}
+				if replayed.tick != recorded.tick || replayed.snake.len() != recorded.snake.len() {
+					t.Errorf("seed %d: replayed tick %d and length %d, recorded %d and %d",
+						seed, replayed.tick, replayed.snake.len(), recorded.tick, recorded.snake.len())
+				}
+			}
+		})
+	}
+}
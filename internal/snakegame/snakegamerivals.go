@@ -0,0 +1,434 @@
+package snakegame
+
+import (
+	"fmt"
+)
+
+// Snake of another player sharing the board, humans have no bot
+type rival struct {
+	snake snake
+	// Direction of the next move and of the last one, turns are checked against the last one
+	direction Direction
+	heading   Direction
+	growth    int
+	score     int
+	alive     bool
+	crashedAt int
+	bot       Bot
+}
+
+// Planned move of a rival
+type rivalMove struct {
+	head      Point
+	direction Direction
+	ok        bool
+}
+
+// Computer-controlled snake
+type Bot interface {
+	// Pick the direction for the next tick
+	Turn(view BotView) Direction
+}
+
+// Read-only view of the game given to bots, seen from the bot's snake
+type BotView interface {
+	Size() (hight int, width int)
+	Head() Point
+	Direction() Direction
+	// Directions of the board grid
+	Directions() []Direction
+	IsFree(p Point) bool
+	// Resolve a step with the board topology, false if it kills
+	Step(p Point, d Direction) (Point, Direction, bool)
+	// Deterministic random value in [0, n)
+	Intn(n int) int
+}
+
+// Final place of a player
+type Standing struct {
+	Score int
+	Alive bool
+}
+
+// Add snakes of other players: the humans steered by the commands of players 1 to humans,
+// then the bots. They move, eat and collide like the player's snake and stay on the board
+// once crashed, entities, power-ups and the game events only concern the player's snake.
+// Outliving every rival wins the game
+func WithRivals(humans int, bots ...Bot) Option {
+	return func(game *SnakeGame) {
+		game.rivals = make([]rival, humans+len(bots))
+		for i, bot := range bots {
+			game.rivals[humans+i].bot = bot
+		}
+	}
+}
+
+// Serializable bot description, e.g. for saves
+type BotConfig struct {
+	Name  string `json:"name"`
+	Depth int    `json:"depth,omitempty"`
+}
+
+// Build the bot described by the config
+func (c BotConfig) Bot() (Bot, error) {
+	switch c.Name {
+	case "space":
+		return SpaceBot{Depth: c.Depth}, nil
+	default:
+		return nil, fmt.Errorf("unknown bot %q", c.Name)
+	}
+}
+
+// Describe a built-in bot for saving
+func botConfig(bot Bot) (BotConfig, error) {
+	switch b := bot.(type) {
+	case SpaceBot:
+		return BotConfig{Name: "space", Depth: b.Depth}, nil
+	default:
+		return BotConfig{}, fmt.Errorf("bot %T can't be saved", bot)
+	}
+}
+
+// Score and state of every player, the player's snake first
+func (game *SnakeGame) Standings() []Standing {
+	standings := []Standing{{Score: game.score, Alive: !game.gameOver}}
+	for _, r := range game.rivals {
+		standings = append(standings, Standing{Score: r.score, Alive: r.alive})
+	}
+	return standings
+}
+
+// Spawns of the level, none without a level
+func (game *SnakeGame) spawns() []Spawn {
+	if game.level == nil {
+		return nil
+	}
+	return game.level.Spawns
+}
+
+// Put the rivals on the level spawns after the player's one, without spawns
+// line them up on the middle row with every other one heading down
+func (game *SnakeGame) spawnRivals() {
+	count := len(game.rivals) + 1
+	spawns := game.spawns()
+	if len(spawns) > 0 && len(spawns) < count {
+		panic("Expected a level spawn for every snake")
+	}
+	if len(spawns) == 0 && count > game.board.width {
+		panic("Expected no more snakes than the board width")
+	}
+	up, _ := game.grid.Resolve(DirectionUp, DirectionUp)
+	down, _ := game.grid.Resolve(DirectionDown, DirectionDown)
+	for i := range game.rivals {
+		p := Point{X: (i + 2) * game.board.width / (count + 1), Y: game.board.hight / 2}
+		direction := up
+		if i%2 == 0 {
+			direction = down
+		}
+		if len(spawns) > 0 {
+			p = spawns[i+1].At
+			direction, _ = game.grid.Resolve(spawns[i+1].Direction, spawns[i+1].Direction)
+		}
+		if game.board.get(p) != CellEmpty {
+			panic("Expected free spawn cells for the rivals")
+		}
+		game.rivals[i].alive = true
+		game.rivals[i].direction, game.rivals[i].heading = direction, direction
+		game.placeRival(i, []Point{p})
+	}
+}
+
+// Put the rival's snake on the board, head first
+func (game *SnakeGame) placeRival(i int, points []Point) {
+	r := &game.rivals[i]
+	r.snake.reset(points)
+	for j := len(points) - 1; j >= 0; j-- {
+		if j == 0 {
+			game.occupy(points[j], CellRivalHead)
+		} else {
+			game.occupy(points[j], CellRivalTail)
+		}
+	}
+}
+
+// Deep copy of the rivals
+func cloneRivals(rivals []rival) []rival {
+	clones := make([]rival, len(rivals))
+	for i, r := range rivals {
+		clones[i] = r
+		clones[i].snake.reset(r.snake.points())
+	}
+	return clones
+}
+
+// Apply a turn of a human rival, it's checked against the direction of the last move
+func (game *SnakeGame) turnRival(command Command) {
+	i := command.Player - 1
+	if i < 0 || i >= len(game.rivals) || game.rivals[i].bot != nil || !game.rivals[i].alive {
+		return
+	}
+	r := &game.rivals[i]
+	direction := command.Direction
+	game.applyRulesAs(command.Player, func(rule Rule, ctx RuleContext) {
+		direction = rule.OnTurn(ctx, direction)
+	})
+	direction, ok := game.grid.Resolve(direction, r.heading)
+	if ok && direction != oppositeDirections[r.heading] {
+		r.direction = direction
+	}
+}
+
+// Let the bots turn and find the next head of every rival, bouncing ones turn around
+func (game *SnakeGame) planRivals() []rivalMove {
+	moves := make([]rivalMove, len(game.rivals))
+	for i := range game.rivals {
+		r := &game.rivals[i]
+		if !r.alive {
+			continue
+		}
+		if r.bot != nil {
+			direction, ok := game.grid.Resolve(r.bot.Turn(botView{game: game, rival: r}), r.heading)
+			if ok && direction != oppositeDirections[r.heading] {
+				r.direction = direction
+			}
+		}
+		game.applyRulesAs(i+1, func(rule Rule, ctx RuleContext) { rule.BeforeMove(ctx) })
+
+		m := &moves[i]
+		m.head, m.direction, m.ok = game.topology.Step(game.grid, r.snake.headPoint(), r.direction, game.board.hight, game.board.width)
+		if m.ok && m.direction == oppositeDirections[r.direction] && r.snake.len() > 1 {
+			m.head, m.direction, m.ok = game.reverseBody(&r.snake, r.direction, CellRivalHead, CellRivalTail)
+		}
+		if m.ok {
+			m.head, m.direction, m.ok = game.throughPortals(m.head, m.direction)
+		}
+	}
+	return moves
+}
+
+// Check if a rival is about to move onto the point, heads meeting on a cell crash both
+func (game *SnakeGame) rivalTarget(moves []rivalMove, p Point) bool {
+	for i, m := range moves {
+		if game.rivals[i].alive && m.ok && m.head == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Move the rivals after the player's snake headed for the target. The crashes are found
+// on the board before any rival moves, so the tail end of another snake is still in the way
+func (game *SnakeGame) moveRivals(moves []rivalMove, target rivalMove) {
+	if len(game.rivals) == 0 {
+		return
+	}
+	targets := make(map[Point]int)
+	if target.ok {
+		targets[target.head]++
+	}
+	for i, m := range moves {
+		if game.rivals[i].alive && m.ok {
+			targets[m.head]++
+		}
+	}
+
+	crashed := make([]bool, len(game.rivals))
+	for i := range game.rivals {
+		r := &game.rivals[i]
+		if !r.alive {
+			continue
+		}
+		m := moves[i]
+		if !m.ok {
+			crashed[i] = game.collideAs(i+1, CollisionBorder, m.head, true)
+			continue
+		}
+		tailEnd := r.snake.at(r.snake.len() - 1)
+		switch cell := game.board.get(m.head); {
+		case targets[m.head] > 1 || cell == CellSnakeHead || cell == CellSnakeTail:
+			crashed[i] = game.collideAs(i+1, CollisionRival, m.head, true)
+		case cell == CellRivalHead || cell == CellRivalTail:
+			if game.rivalAt(m.head) != i {
+				crashed[i] = game.collideAs(i+1, CollisionRival, m.head, true)
+			} else if r.growth > 0 || m.head != tailEnd {
+				crashed[i] = game.collideAs(i+1, CollisionSelf, m.head, true)
+			}
+		case cell != CellEmpty && cell != CellFood && !game.isPowerUp(m.head):
+			crashed[i] = game.collideAs(i+1, CollisionWall, m.head, true)
+		}
+	}
+
+	for i := range game.rivals {
+		r := &game.rivals[i]
+		if !r.alive {
+			continue
+		}
+		if crashed[i] {
+			r.alive = false
+			r.crashedAt = game.tick
+			continue
+		}
+		m := moves[i]
+		eats := game.board.get(m.head) == CellFood
+		if game.isPowerUp(m.head) {
+			game.powerUp = nil
+		}
+		if r.growth > 0 {
+			r.growth--
+		} else {
+			game.vacate(r.snake.popTail())
+		}
+		if r.snake.len() > 0 {
+			game.board.set(r.snake.headPoint(), CellRivalTail)
+		}
+		r.snake.pushHead(m.head)
+		r.direction, r.heading = m.direction, m.direction
+		game.occupy(m.head, CellRivalHead)
+		game.applyRulesAs(i+1, func(rule Rule, ctx RuleContext) { rule.AfterMove(ctx) })
+		if eats {
+			game.eaten++
+			game.applyRulesAs(i+1, func(rule Rule, ctx RuleContext) { rule.OnEat(ctx) })
+			game.refillFood()
+		}
+	}
+
+	if !game.gameOver && !game.won && !game.rivalsAlive() {
+		game.win(EventOutlived)
+	}
+}
+
+// Index of the rival with a segment on the point, -1 if there is none
+func (game *SnakeGame) rivalAt(p Point) int {
+	for i := range game.rivals {
+		for j := 0; j < game.rivals[i].snake.len(); j++ {
+			if game.rivals[i].snake.at(j) == p {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Crash the rival caught on the point, e.g. by a collapsing ring
+func (game *SnakeGame) crashRivalAt(p Point, kind CollisionKind) {
+	i := game.rivalAt(p)
+	if i < 0 || !game.rivals[i].alive || !game.collideAs(i+1, kind, p, true) {
+		return
+	}
+	game.rivals[i].alive = false
+	game.rivals[i].crashedAt = game.tick
+}
+
+// Check if any rival is still moving
+func (game *SnakeGame) rivalsAlive() bool {
+	for _, r := range game.rivals {
+		if r.alive {
+			return true
+		}
+	}
+	return false
+}
+
+// Outcome of the crash ending the game, crashing on the same tick as the last rivals is a draw
+func (game *SnakeGame) crashOutcome() Outcome {
+	if len(game.rivals) == 0 || game.rivalsAlive() {
+		return OutcomeGameOver
+	}
+	for _, r := range game.rivals {
+		if r.crashedAt == game.tick {
+			return OutcomeDraw
+		}
+	}
+	return OutcomeGameOver
+}
+
+// BotView implementation backed by the game
+type botView struct {
+	game  *SnakeGame
+	rival *rival
+}
+
+func (v botView) Size() (int, int) {
+	return v.game.board.hight, v.game.board.width
+}
+
+func (v botView) Head() Point {
+	return v.rival.snake.headPoint()
+}
+
+func (v botView) Direction() Direction {
+	return v.rival.heading
+}
+
+func (v botView) Directions() []Direction {
+	return v.game.grid.Directions()
+}
+
+func (v botView) IsFree(p Point) bool {
+	if !v.game.board.contains(p) {
+		return false
+	}
+	cell := v.game.board.get(p)
+	return cell == CellEmpty || cell == CellFood
+}
+
+func (v botView) Step(p Point, d Direction) (Point, Direction, bool) {
+	return v.game.topology.Step(v.game.grid, p, d, v.game.board.hight, v.game.board.width)
+}
+
+func (v botView) Intn(n int) int {
+	return v.game.random.intn(n)
+}
+
+// Bot turning toward the largest free area reachable within Depth cells
+type SpaceBot struct {
+	Depth int
+}
+
+func (b SpaceBot) Turn(view BotView) Direction {
+	current := view.Direction()
+	candidates := []Direction{current}
+	for _, d := range view.Directions() {
+		if d != current && d != oppositeDirections[current] {
+			candidates = append(candidates, d)
+		}
+	}
+
+	best, bestSpace, ties := current, -1, 0
+	for _, d := range candidates {
+		p, _, ok := view.Step(view.Head(), d)
+		space := -1
+		if ok && view.IsFree(p) {
+			space = b.space(view, p)
+		}
+		switch {
+		case space > bestSpace:
+			best, bestSpace, ties = d, space, 1
+		case space == bestSpace:
+			ties++
+			if view.Intn(ties) == 0 {
+				best = d
+			}
+		}
+	}
+	return best
+}
+
+// Number of free cells reachable from the point, capped by the depth
+func (b SpaceBot) space(view BotView, start Point) int {
+	depth := max(b.Depth, 1)
+	seen := map[Point]bool{start: true}
+	queue := []Point{start}
+	for len(queue) > 0 && len(seen) < depth {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range view.Directions() {
+			next, _, ok := view.Step(p, d)
+			if ok && !seen[next] && view.IsFree(next) {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return len(seen)
+}
@@ -0,0 +1,324 @@
+package snakegame
+
+import (
+	"fmt"
+	"time"
+)
+
+// Game rule hooks, rules are called in the registration order
+type Rule interface {
+	// Start of every tick
+	OnTick(ctx RuleContext)
+	// Turn requested by the player, returns the direction to apply
+	OnTurn(ctx RuleContext, direction Direction) Direction
+	// Right before the head moves, the direction may still be changed
+	BeforeMove(ctx RuleContext)
+	// Right after the head and body moved
+	AfterMove(ctx RuleContext)
+	// The head reached the food
+	OnEat(ctx RuleContext)
+	// The head hit the border, the body, an entity or a wall, rules may change collision.Fatal
+	OnCollision(ctx RuleContext, collision *Collision)
+}
+
+type CollisionKind int8
+
+const (
+	CollisionBorder CollisionKind = iota
+	CollisionSelf
+	CollisionEntity
+	CollisionWall
+	// Another player's snake
+	CollisionRival
+)
+
+// Collision details passed to the rules
+type Collision struct {
+	Kind  CollisionKind
+	At    Point
+	Fatal bool
+}
+
+// No-op implementation of every hook, embed it to override only needed ones
+type BaseRule struct{}
+
+func (BaseRule) OnTick(ctx RuleContext)                                {}
+func (BaseRule) OnTurn(ctx RuleContext, direction Direction) Direction { return direction }
+func (BaseRule) BeforeMove(ctx RuleContext)                            {}
+func (BaseRule) AfterMove(ctx RuleContext)                             {}
+func (BaseRule) OnEat(ctx RuleContext)                                 {}
+func (BaseRule) OnCollision(ctx RuleContext, collision *Collision)     {}
+
+// Game state available to the rules, the snake ones are of the player the hook is called for.
+// Hooks run for the rivals as well except for OnTick
+type RuleContext struct {
+	game   *SnakeGame
+	player int
+}
+
+// Player of the snake, 0 for the player's snake and 1 on for the rivals
+func (ctx RuleContext) Player() int {
+	return ctx.player
+}
+
+// Rival of the context, nil for the player's snake
+func (ctx RuleContext) rival() *rival {
+	if ctx.player == 0 {
+		return nil
+	}
+	return &ctx.game.rivals[ctx.player-1]
+}
+
+func (ctx RuleContext) Tick() int {
+	return ctx.game.tick
+}
+
+func (ctx RuleContext) Score() int {
+	if r := ctx.rival(); r != nil {
+		return r.score
+	}
+	return ctx.game.score
+}
+
+// Add points, the player's ones are doubled while the score doubler is active
+func (ctx RuleContext) AddScore(points int) {
+	if r := ctx.rival(); r != nil {
+		r.score += points
+		return
+	}
+	ctx.game.score += points * ctx.game.scoreMultiplier()
+}
+
+// Number of food items eaten so far
+func (ctx RuleContext) Eaten() int {
+	return ctx.game.eaten
+}
+
+func (ctx RuleContext) Length() int {
+	if r := ctx.rival(); r != nil {
+		return r.snake.len()
+	}
+	return ctx.game.snake.len()
+}
+
+func (ctx RuleContext) Head() Point {
+	if r := ctx.rival(); r != nil {
+		return r.snake.headPoint()
+	}
+	return ctx.game.snake.headPoint()
+}
+
+func (ctx RuleContext) Direction() Direction {
+	if r := ctx.rival(); r != nil {
+		return r.direction
+	}
+	return ctx.game.moveDirection
+}
+
+func (ctx RuleContext) SetDirection(direction Direction) {
+	if r := ctx.rival(); r != nil {
+		r.direction = direction
+		return
+	}
+	ctx.game.moveDirection = direction
+}
+
+// Add segments, the tail stays in place for that many ticks
+func (ctx RuleContext) Grow(segments int) {
+	if r := ctx.rival(); r != nil {
+		r.growth += segments
+		return
+	}
+	ctx.game.growth += segments
+}
+
+// Game time played so far, pauses excluded
+func (ctx RuleContext) Elapsed() time.Duration {
+	return ctx.game.elapsed
+}
+
+func (ctx RuleContext) SetElapsed(elapsed time.Duration) {
+	ctx.game.elapsed = elapsed
+}
+
+// Number of border rings turned into walls
+func (ctx RuleContext) Collapsed() int {
+	return ctx.game.collapsed
+}
+
+// Tick of the next ring collapse, zero if none is scheduled
+func (ctx RuleContext) NextCollapse() int {
+	return ctx.game.nextCollapse
+}
+
+// Collapse the next border ring at the tick, it's telegraphed for the last warning ticks
+func (ctx RuleContext) ScheduleCollapse(tick int, warning int) {
+	ctx.game.nextCollapse = tick
+	ctx.game.collapseWarning = warning
+}
+
+func (ctx RuleContext) TickInterval() time.Duration {
+	return ctx.game.tickInterval
+}
+
+func (ctx RuleContext) SetTickInterval(interval time.Duration) {
+	ctx.game.tickInterval = interval
+}
+
+// Classic rule set: grow by one segment and score one point per food,
+// every collision is fatal
+func DefaultRules() []Rule {
+	return []Rule{GrowOnEat{Segments: 1}, ScoreOnEat{Points: 1}}
+}
+
+// Replace the default rule set
+func WithRules(rules ...Rule) Option {
+	return func(game *SnakeGame) {
+		game.rules = rules
+	}
+}
+
+// Serializable rule description, e.g. for saves
+type RuleConfig struct {
+	Name     string        `json:"name"`
+	Segments int           `json:"segments,omitempty"`
+	Points   int           `json:"points,omitempty"`
+	Foods    int           `json:"foods,omitempty"`
+	Percent  int           `json:"percent,omitempty"`
+	Min      time.Duration `json:"min,omitempty"`
+}
+
+// Build the rule described by the config
+func (c RuleConfig) Rule() (Rule, error) {
+	switch c.Name {
+	case "grow-on-eat":
+		return GrowOnEat{Segments: c.Segments}, nil
+	case "score-on-eat":
+		return ScoreOnEat{Points: c.Points}, nil
+	case "no-self-collision":
+		return NoSelfCollision{}, nil
+	case "mirror-controls":
+		return MirrorControls{}, nil
+	case "speed-up":
+		return SpeedUp{Foods: c.Foods, Percent: c.Percent, Min: c.Min}, nil
+	case "trail":
+		return Trail{}, nil
+	default:
+		return nil, fmt.Errorf("unknown rule %q", c.Name)
+	}
+}
+
+// Describe a built-in rule for saving
+func ruleConfig(rule Rule) (RuleConfig, error) {
+	switch r := rule.(type) {
+	case GrowOnEat:
+		return RuleConfig{Name: "grow-on-eat", Segments: r.Segments}, nil
+	case ScoreOnEat:
+		return RuleConfig{Name: "score-on-eat", Points: r.Points}, nil
+	case NoSelfCollision:
+		return RuleConfig{Name: "no-self-collision"}, nil
+	case MirrorControls:
+		return RuleConfig{Name: "mirror-controls"}, nil
+	case SpeedUp:
+		return RuleConfig{Name: "speed-up", Foods: r.Foods, Percent: r.Percent, Min: r.Min}, nil
+	case Trail:
+		return RuleConfig{Name: "trail"}, nil
+	default:
+		return RuleConfig{}, fmt.Errorf("rule %T can't be saved", rule)
+	}
+}
+
+// Grow by the number of segments per food
+type GrowOnEat struct {
+	BaseRule
+	Segments int
+}
+
+func (r GrowOnEat) OnEat(ctx RuleContext) {
+	ctx.Grow(r.Segments)
+}
+
+// Add points per food
+type ScoreOnEat struct {
+	BaseRule
+	Points int
+}
+
+func (r ScoreOnEat) OnEat(ctx RuleContext) {
+	ctx.AddScore(r.Points)
+}
+
+// Crawl through the own body
+type NoSelfCollision struct {
+	BaseRule
+}
+
+func (NoSelfCollision) OnCollision(ctx RuleContext, collision *Collision) {
+	if collision.Kind == CollisionSelf {
+		collision.Fatal = false
+	}
+}
+
+// Arrows turn the snake to the opposite side
+type MirrorControls struct {
+	BaseRule
+}
+
+func (MirrorControls) OnTurn(ctx RuleContext, direction Direction) Direction {
+	return oppositeDirections[direction]
+}
+
+// Shorten the tick interval by Percent every Foods eaten down to Min
+type SpeedUp struct {
+	BaseRule
+	Foods   int
+	Percent int
+	Min     time.Duration
+}
+
+func (r SpeedUp) OnEat(ctx RuleContext) {
+	if r.Foods <= 0 || ctx.Eaten()%r.Foods != 0 {
+		return
+	}
+	ctx.SetTickInterval(max(r.Min, ctx.TickInterval()*time.Duration(100-r.Percent)/100))
+}
+
+// Light-cycle trail: the body never shrinks and every move scores a point
+type Trail struct {
+	BaseRule
+}
+
+func (Trail) BeforeMove(ctx RuleContext) {
+	ctx.Grow(1)
+}
+
+func (Trail) AfterMove(ctx RuleContext) {
+	ctx.AddScore(1)
+}
+
+// Run the hook for every rule on the player's snake
+func (game *SnakeGame) applyRules(hook func(rule Rule, ctx RuleContext)) {
+	game.applyRulesAs(0, hook)
+}
+
+// Run the hook for every rule on the snake of the player
+func (game *SnakeGame) applyRulesAs(player int, hook func(rule Rule, ctx RuleContext)) {
+	ctx := RuleContext{game: game, player: player}
+	for _, rule := range game.rules {
+		hook(rule, ctx)
+	}
+}
+
+// Ask the rules if the collision ends the game
+func (game *SnakeGame) collide(kind CollisionKind, at Point, fatal bool) bool {
+	return game.collideAs(0, kind, at, fatal)
+}
+
+// Ask the rules if the collision crashes the snake of the player
+func (game *SnakeGame) collideAs(player int, kind CollisionKind, at Point, fatal bool) bool {
+	collision := Collision{Kind: kind, At: at, Fatal: fatal}
+	game.applyRulesAs(player, func(rule Rule, ctx RuleContext) {
+		rule.OnCollision(ctx, &collision)
+	})
+	return collision.Fatal
+}
@@ -0,0 +1,353 @@
+package snakegame
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Current save file format version, version 1 saves have no rules and food
+// placement and keep the ones of the resuming game, version 3 added the rivals
+const saveVersion = 3
+
+// Serialized game state
+type saveState struct {
+	Version int `json:"version"`
+
+	BoardHight int `json:"boardHight"`
+	BoardWidth int `json:"boardWidth"`
+
+	Portals       []PortalPair   `json:"portals,omitempty"`
+	Walls         []Point        `json:"walls,omitempty"`
+	Exit          *Point         `json:"exit,omitempty"`
+	Entities      []EntityConfig `json:"entities,omitempty"`
+	Snake         [][2]int       `json:"snake"`
+	Food          [2]int         `json:"food"`
+	NoFood        bool           `json:"noFood,omitempty"`
+	FoodSpawned   int            `json:"foodSpawned,omitempty"`
+	MoveDirection Direction      `json:"moveDirection"`
+	AteFood       bool           `json:"ateFood"`
+	Growth        int            `json:"growth,omitempty"`
+	Eaten         int            `json:"eaten,omitempty"`
+	Rivals        []saveRival    `json:"rivals,omitempty"`
+
+	PowerUp *powerUp            `json:"powerUp,omitempty"`
+	Effects map[PowerUpKind]int `json:"effects,omitempty"`
+
+	Score        int           `json:"score"`
+	Tick         int           `json:"tick"`
+	Elapsed      time.Duration `json:"elapsed,omitempty"`
+	Collapsed    int           `json:"collapsed,omitempty"`
+	NextCollapse int           `json:"nextCollapse,omitempty"`
+	Warning      int           `json:"warning,omitempty"`
+	TickInterval time.Duration `json:"tickInterval,omitempty"`
+	Random       uint64        `json:"random"`
+
+	Rules saveRules `json:"rules"`
+}
+
+// Serialized rival snake, humans have no bot
+type saveRival struct {
+	Snake     []Point    `json:"snake"`
+	Direction Direction  `json:"direction"`
+	Growth    int        `json:"growth,omitempty"`
+	Score     int        `json:"score"`
+	Alive     bool       `json:"alive"`
+	CrashedAt int        `json:"crashedAt,omitempty"`
+	Bot       *BotConfig `json:"bot,omitempty"`
+}
+
+// Serialized game rules
+type saveRules struct {
+	BorderKiller bool           `json:"borderKiller"`
+	Topology     *EdgeTopology  `json:"topology,omitempty"`
+	Practice     bool           `json:"practice,omitempty"`
+	TurnBased    bool           `json:"turnBased,omitempty"`
+	Grid         string         `json:"grid,omitempty"`
+	PowerUps     *PowerUpConfig `json:"powerUps,omitempty"`
+	Mode         *ModeConfig    `json:"mode,omitempty"`
+	Rules        []RuleConfig   `json:"rules,omitempty"`
+	Food         *FoodConfig    `json:"food,omitempty"`
+}
+
+// Write full game state to w
+func (game *SnakeGame) Save(w io.Writer) error {
+	state := saveState{
+		Version:       saveVersion,
+		BoardHight:    game.board.hight,
+		BoardWidth:    game.board.width,
+		Food:          [2]int{game.food.X, game.food.Y},
+		NoFood:        game.board.get(game.food) != CellFood,
+		FoodSpawned:   game.foodSpawned,
+		Portals:       game.portalPairs,
+		Walls:         game.walls,
+		Exit:          game.exit,
+		MoveDirection: game.moveDirection,
+		AteFood:       game.growth > 0,
+		Growth:        game.growth,
+		Eaten:         game.eaten,
+		Score:         game.score,
+		Tick:          game.tick,
+		Elapsed:       game.elapsed,
+		Collapsed:     game.collapsed,
+		NextCollapse:  game.nextCollapse,
+		Warning:       game.collapseWarning,
+		TickInterval:  game.tickInterval,
+		Random:        game.random.state,
+		PowerUp:       game.powerUp,
+		Rules:         saveRules{Practice: game.practice, TurnBased: game.turnBased},
+	}
+	if game.powerUps.Every > 0 {
+		state.Rules.PowerUps = &game.powerUps
+	}
+	for kind, until := range game.effects {
+		if until > game.tick {
+			if state.Effects == nil {
+				state.Effects = make(map[PowerUpKind]int)
+			}
+			state.Effects[PowerUpKind(kind)] = until
+		}
+	}
+	topology, ok := game.topology.(EdgeTopology)
+	if !ok {
+		return fmt.Errorf("topology %T can't be saved", game.topology)
+	}
+	state.Rules.BorderKiller = topology == Walled
+	state.Rules.Topology = &topology
+	grid, err := gridName(game.grid)
+	if err != nil {
+		return err
+	}
+	state.Rules.Grid = grid
+	mode, err := modeConfig(game.mode)
+	if err != nil {
+		return err
+	}
+	state.Rules.Mode = &mode
+	for _, rule := range game.rules {
+		config, err := ruleConfig(rule)
+		if err != nil {
+			return err
+		}
+		state.Rules.Rules = append(state.Rules.Rules, config)
+	}
+	food, err := foodConfig(game.foodSpawner)
+	if err != nil {
+		return err
+	}
+	state.Rules.Food = &food
+	for _, entity := range game.entities {
+		config, err := entityConfig(entity)
+		if err != nil {
+			return err
+		}
+		state.Entities = append(state.Entities, config)
+	}
+	for _, v := range game.snake.points() {
+		state.Snake = append(state.Snake, [2]int{v.X, v.Y})
+	}
+	for _, r := range game.rivals {
+		saved := saveRival{Snake: r.snake.points(), Direction: r.heading, Growth: r.growth, Score: r.score, Alive: r.alive, CrashedAt: r.crashedAt}
+		if r.bot != nil {
+			config, err := botConfig(r.bot)
+			if err != nil {
+				return err
+			}
+			saved.Bot = &config
+		}
+		state.Rivals = append(state.Rivals, saved)
+	}
+
+	return json.NewEncoder(w).Encode(state)
+}
+
+// Replace game state with the one saved to r, game must be initialized
+func (game *SnakeGame) Load(r io.Reader) error {
+	var state saveState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return err
+	}
+	if err := state.validate(); err != nil {
+		return err
+	}
+
+	grid, err := ParseGrid(state.Rules.Grid)
+	if err != nil {
+		return err
+	}
+	game.grid = grid
+	game.board.init(state.BoardHight, state.BoardWidth, game.viewportSize)
+	game.board.alignRows = game.grid == Grid(HexGrid{})
+	game.portalPairs = state.Portals
+	game.walls = state.Walls
+	game.exit = state.Exit
+	game.collapsed = state.Collapsed
+	game.nextCollapse = state.NextCollapse
+	game.collapseWarning = state.Warning
+	game.placeLayout()
+	game.entities = game.entities[:0]
+	for _, config := range state.Entities {
+		entity, err := config.Entity()
+		if err != nil {
+			return err
+		}
+		game.entities = append(game.entities, entity)
+	}
+	var snake []Point
+	for _, p := range state.Snake {
+		snake = append(snake, Point{X: p[0], Y: p[1]})
+	}
+	game.overlaps = make(map[Point]int)
+	game.placeSnake(snake)
+	game.rivals = make([]rival, len(state.Rivals))
+	for i, saved := range state.Rivals {
+		r := &game.rivals[i]
+		r.direction, r.heading = saved.Direction, saved.Direction
+		r.growth, r.score, r.alive, r.crashedAt = saved.Growth, saved.Score, saved.Alive, saved.CrashedAt
+		if saved.Bot != nil {
+			bot, err := saved.Bot.Bot()
+			if err != nil {
+				return err
+			}
+			r.bot = bot
+		}
+		game.placeRival(i, saved.Snake)
+	}
+	game.food = Point{X: state.Food[0], Y: state.Food[1]}
+	if !state.NoFood {
+		game.board.set(game.food, CellFood)
+	}
+	game.powerUp = state.PowerUp
+	if game.powerUp != nil {
+		game.board.set(game.powerUp.At, powerUpCells[game.powerUp.Kind])
+	}
+	game.effects = [powerUpKinds]int{}
+	for kind, until := range state.Effects {
+		game.effects[kind] = until
+	}
+	game.powerUps = PowerUpConfig{}
+	if state.Rules.PowerUps != nil {
+		game.powerUps = *state.Rules.PowerUps
+	}
+	game.foodSpawned = state.FoodSpawned
+	game.moveDirection = state.MoveDirection
+	game.growth = state.Growth
+	if game.growth == 0 && state.AteFood {
+		game.growth = 1
+	}
+	game.eaten = state.Eaten
+	if game.eaten == 0 && state.Version == 1 {
+		game.eaten = state.Score
+	}
+	game.score = state.Score
+	game.tick = state.Tick
+	game.elapsed = state.Elapsed
+	if state.TickInterval > 0 {
+		game.tickInterval = state.TickInterval
+	}
+	game.random.state = state.Random
+	if state.Rules.Topology != nil {
+		game.topology = *state.Rules.Topology
+	} else if state.Rules.BorderKiller {
+		game.topology = Walled
+	} else {
+		game.topology = Torus
+	}
+	game.mode = Classic{}
+	if state.Rules.Mode != nil {
+		mode, err := state.Rules.Mode.Mode()
+		if err != nil {
+			return err
+		}
+		game.mode = mode
+	}
+	if state.Version > 1 {
+		game.rules = nil
+		for _, config := range state.Rules.Rules {
+			rule, err := config.Rule()
+			if err != nil {
+				return err
+			}
+			game.rules = append(game.rules, rule)
+		}
+	}
+	if state.Rules.Food != nil {
+		spawner, err := state.Rules.Food.Spawner()
+		if err != nil {
+			return err
+		}
+		game.foodSpawner = spawner
+	}
+	game.practice = game.practice || state.Rules.Practice
+	game.turnBased = game.turnBased || state.Rules.TurnBased
+	game.gameOver = false
+	game.paused = false
+	game.history.init(len(game.history.items))
+	return nil
+}
+
+// Check that saved state is consistent
+func (state *saveState) validate() error {
+	if state.Version < 1 || state.Version > saveVersion {
+		return fmt.Errorf("unsupported save version %d", state.Version)
+	}
+	if state.BoardHight > MaxBoardSize || state.BoardHight <= 0 || state.BoardWidth > MaxBoardSize || state.BoardWidth <= 0 {
+		return errors.New("saved board size is out of range")
+	}
+	if len(state.Snake) == 0 {
+		return errors.New("saved snake is empty")
+	}
+	grid, err := ParseGrid(state.Rules.Grid)
+	if err != nil {
+		return err
+	}
+	if state.Rules.Topology != nil {
+		if err := checkGrid(grid, *state.Rules.Topology, state.BoardHight); err != nil {
+			return err
+		}
+	}
+	if state.MoveDirection < DirectionUp || state.MoveDirection > DirectionUpLeft {
+		return errors.New("saved direction is invalid")
+	}
+
+	points := append([][2]int{state.Food}, state.Snake...)
+	for _, r := range state.Rivals {
+		if len(r.Snake) == 0 {
+			return errors.New("saved rival is empty")
+		}
+		if r.Direction < DirectionUp || r.Direction > DirectionUpLeft {
+			return errors.New("saved rival direction is invalid")
+		}
+		for _, p := range r.Snake {
+			points = append(points, [2]int{p.X, p.Y})
+		}
+	}
+	for _, pair := range state.Portals {
+		points = append(points, [2]int{pair.A.X, pair.A.Y}, [2]int{pair.B.X, pair.B.Y})
+	}
+	for _, p := range state.Walls {
+		points = append(points, [2]int{p.X, p.Y})
+	}
+	if state.Exit != nil {
+		points = append(points, [2]int{state.Exit.X, state.Exit.Y})
+	}
+	for _, config := range state.Entities {
+		for _, p := range config.Points() {
+			points = append(points, [2]int{p.X, p.Y})
+		}
+	}
+	if state.PowerUp != nil {
+		if state.PowerUp.Kind < 0 || state.PowerUp.Kind >= powerUpKinds {
+			return errors.New("saved power-up is invalid")
+		}
+		points = append(points, [2]int{state.PowerUp.At.X, state.PowerUp.At.Y})
+	}
+	for _, p := range points {
+		if p[0] < 0 || p[0] >= state.BoardWidth || p[1] < 0 || p[1] >= state.BoardHight {
+			return errors.New("saved coordinates are out of the board")
+		}
+	}
+	return nil
+}
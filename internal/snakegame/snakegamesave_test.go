@@ -0,0 +1,49 @@
+package snakegame
+
+import (
+	"bytes"
+	"testing"
+)
+
+// A loaded game saves the same state and goes on like the saved one,
+// games are saved on quit so the ones over before are left out
+func TestSaveLoadRoundTrip(t *testing.T) {
+	for _, c := range replayCases {
+		t.Run(c.name, func(t *testing.T) {
+			played := 0
+			for seed := int64(1); seed <= 10; seed++ {
+				options := append([]Option{WithSeed(seed)}, c.options...)
+				saved := headlessGame(c.hight, c.width, options...)
+				if playScripted(saved, 15, uint64(seed)).Outcome != OutcomeQuit {
+					continue
+				}
+				played++
+				saved.quit = false
+				var first bytes.Buffer
+				if err := saved.Save(&first); err != nil {
+					t.Fatal(err)
+				}
+
+				loaded := headlessGame(c.hight, c.width, options...)
+				if err := loaded.Load(bytes.NewReader(first.Bytes())); err != nil {
+					t.Fatalf("seed %d: %v", seed, err)
+				}
+				var second bytes.Buffer
+				if err := loaded.Save(&second); err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(first.Bytes(), second.Bytes()) {
+					t.Fatalf("seed %d: loaded game saves\n%s\nsaved one\n%s", seed, second.Bytes(), first.Bytes())
+				}
+
+				want := playScripted(saved, 100, uint64(seed)+100)
+				if got := playScripted(loaded, 100, uint64(seed)+100); got != want {
+					t.Errorf("seed %d: loaded game ends %+v, saved one %+v", seed, got, want)
+				}
+			}
+			if played == 0 {
+				t.Fatal("every game was over before saving")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package snakegame
+
+// Snake body kept in a ring buffer, index 0 is the head
+type snake struct {
+	body   []Point
+	head   int
+	length int
+}
+
+// Replace the body with the points, head first
+func (s *snake) reset(points []Point) {
+	s.body = make([]Point, max(len(points), 16))
+	copy(s.body, points)
+	s.head = 0
+	s.length = len(points)
+}
+
+// Number of segments
+func (s *snake) len() int {
+	return s.length
+}
+
+// Segment by index counting from the head
+func (s *snake) at(i int) Point {
+	return s.body[(s.head+i)%len(s.body)]
+}
+
+// Head position
+func (s *snake) headPoint() Point {
+	return s.body[s.head]
+}
+
+// Add new head, the buffer doubles when full
+func (s *snake) pushHead(p Point) {
+	if s.length == len(s.body) {
+		points := s.points()
+		s.body = make([]Point, max(2*len(points), 16))
+		copy(s.body, points)
+		s.head = 0
+	}
+	s.head = (s.head - 1 + len(s.body)) % len(s.body)
+	s.body[s.head] = p
+	s.length++
+}
+
+// Remove the last segment
+func (s *snake) popTail() Point {
+	s.length--
+	return s.at(s.length)
+}
+
+// Copy of all segments, head first
+func (s *snake) points() []Point {
+	points := make([]Point, s.length)
+	for i := range points {
+		points[i] = s.at(i)
+	}
+	return points
+}
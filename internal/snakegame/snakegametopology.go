@@ -0,0 +1,174 @@
+package snakegame
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Board border behavior
+type Topology interface {
+	// Resolve a step from p in direction d on the grid of the given size,
+	// returns the new position and direction or false if the snake dies.
+	// Turning straight back reverses the snake so that its tail leads
+	Step(grid Grid, p Point, d Direction, hight int, width int) (Point, Direction, bool)
+}
+
+// What happens when the snake crosses a board edge
+type EdgeRule int8
+
+const (
+	// Come out from the opposite edge
+	EdgeWrap EdgeRule = iota
+	// Die
+	EdgeKill
+	// Reflect the heading across the edge, a snake hitting it straight turns around
+	EdgeBounce
+	// Come out from the opposite edge mirrored along it
+	EdgeMirror
+)
+
+var edgeRuleNames = []string{"wrap", "kill", "bounce", "mirror"}
+
+func (r EdgeRule) MarshalText() ([]byte, error) {
+	if r < 0 || int(r) >= len(edgeRuleNames) {
+		return nil, fmt.Errorf("unknown edge rule %d", r)
+	}
+	return []byte(edgeRuleNames[r]), nil
+}
+
+func (r *EdgeRule) UnmarshalText(text []byte) error {
+	for i, name := range edgeRuleNames {
+		if name == string(text) {
+			*r = EdgeRule(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown edge rule %q", text)
+}
+
+// Topology with a separate rule for every edge
+type EdgeTopology struct {
+	Top    EdgeRule `json:"top"`
+	Right  EdgeRule `json:"right"`
+	Bottom EdgeRule `json:"bottom"`
+	Left   EdgeRule `json:"left"`
+}
+
+var (
+	Torus       = EdgeTopology{Top: EdgeWrap, Right: EdgeWrap, Bottom: EdgeWrap, Left: EdgeWrap}
+	Walled      = EdgeTopology{Top: EdgeKill, Right: EdgeKill, Bottom: EdgeKill, Left: EdgeKill}
+	Bouncing    = EdgeTopology{Top: EdgeBounce, Right: EdgeBounce, Bottom: EdgeBounce, Left: EdgeBounce}
+	KleinBottle = EdgeTopology{Top: EdgeMirror, Right: EdgeWrap, Bottom: EdgeMirror, Left: EdgeWrap}
+)
+
+// Parse a preset name or four comma separated edge rules: top,right,bottom,left
+func ParseEdgeTopology(s string) (EdgeTopology, error) {
+	switch s {
+	case "torus", "wrap":
+		return Torus, nil
+	case "walled", "kill":
+		return Walled, nil
+	case "bounce":
+		return Bouncing, nil
+	case "klein":
+		return KleinBottle, nil
+	}
+
+	names := strings.Split(s, ",")
+	if len(names) != 4 {
+		return EdgeTopology{}, fmt.Errorf("expected topology preset or top,right,bottom,left rules, got %q", s)
+	}
+	var rules [4]EdgeRule
+	for i, name := range names {
+		if err := rules[i].UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+			return EdgeTopology{}, err
+		}
+	}
+	return EdgeTopology{Top: rules[0], Right: rules[1], Bottom: rules[2], Left: rules[3]}, nil
+}
+
+// Use the topology instead of the one selected by borderKiller
+func WithTopology(topology Topology) Option {
+	return func(game *SnakeGame) {
+		game.topology = topology
+	}
+}
+
+// Opposite of every direction
+var oppositeDirections = map[Direction]Direction{
+	DirectionUp:        DirectionDown,
+	DirectionRight:     DirectionLeft,
+	DirectionDown:      DirectionUp,
+	DirectionLeft:      DirectionRight,
+	DirectionUpRight:   DirectionDownLeft,
+	DirectionDownRight: DirectionUpLeft,
+	DirectionDownLeft:  DirectionUpRight,
+	DirectionUpLeft:    DirectionDownRight,
+}
+
+func (t EdgeTopology) Step(grid Grid, p Point, d Direction, hight int, width int) (Point, Direction, bool) {
+	next := grid.Neighbor(p, d)
+	xRule, yRule, crossX, crossY := t.crossed(next, hight, width)
+	if !crossX && !crossY {
+		return next, d, true
+	}
+	if (crossX && xRule == EdgeKill) || (crossY && yRule == EdgeKill) {
+		return p, d, false
+	}
+
+	// Bounces go first, a diagonal move through a corner may still wrap on the other edge
+	bounceX, bounceY := crossX && xRule == EdgeBounce, crossY && yRule == EdgeBounce
+	if bounceX || bounceY {
+		d = reflect(d, bounceX, bounceY)
+		next = grid.Neighbor(p, d)
+		xRule, yRule, crossX, crossY = t.crossed(next, hight, width)
+		if (crossX && xRule != EdgeWrap && xRule != EdgeMirror) || (crossY && yRule != EdgeWrap && yRule != EdgeMirror) {
+			return p, d, false
+		}
+	}
+
+	// Wraps and mirrors come out from the opposite edges, mirrors flip the position along them
+	next.X = (next.X + width) % width
+	next.Y = (next.Y + hight) % hight
+	if crossY && yRule == EdgeMirror {
+		next.X = width - 1 - next.X
+	}
+	if crossX && xRule == EdgeMirror {
+		next.Y = hight - 1 - next.Y
+	}
+	return next, d, true
+}
+
+// Rules of the edges the point lies beyond on each axis
+func (t EdgeTopology) crossed(p Point, hight int, width int) (xRule EdgeRule, yRule EdgeRule, crossX bool, crossY bool) {
+	switch {
+	case p.X < 0:
+		xRule, crossX = t.Left, true
+	case p.X >= width:
+		xRule, crossX = t.Right, true
+	}
+	switch {
+	case p.Y < 0:
+		yRule, crossY = t.Top, true
+	case p.Y >= hight:
+		yRule, crossY = t.Bottom, true
+	}
+	return xRule, yRule, crossX, crossY
+}
+
+// Heading with the components across the bounced edges flipped
+func reflect(d Direction, x bool, y bool) Direction {
+	delta := directionDeltas[d]
+	if x {
+		delta.X = -delta.X
+	}
+	if y {
+		delta.Y = -delta.Y
+	}
+	for reflected, other := range directionDeltas {
+		if other == delta {
+			return reflected
+		}
+	}
+	return oppositeDirections[d]
+}
@@ -0,0 +1,91 @@
+package snakegame
+
+import (
+	"testing"
+)
+
+func TestEdgeTopologyStep(t *testing.T) {
+	const hight, width = 4, 5
+	tests := []struct {
+		name      string
+		topology  EdgeTopology
+		grid      Grid
+		from      Point
+		direction Direction
+		to        Point
+		heading   Direction
+		ok        bool
+	}{
+		{"inside", Walled, SquareGrid{}, Point{X: 2, Y: 2}, DirectionUp, Point{X: 2, Y: 1}, DirectionUp, true},
+		{"wrap right", Torus, SquareGrid{}, Point{X: 4, Y: 1}, DirectionRight, Point{X: 0, Y: 1}, DirectionRight, true},
+		{"wrap top", Torus, SquareGrid{}, Point{X: 3, Y: 0}, DirectionUp, Point{X: 3, Y: 3}, DirectionUp, true},
+		{"kill", Walled, SquareGrid{}, Point{X: 0, Y: 0}, DirectionUp, Point{X: 0, Y: 0}, DirectionUp, false},
+		{"bounce left", Bouncing, SquareGrid{}, Point{X: 0, Y: 2}, DirectionLeft, Point{X: 1, Y: 2}, DirectionRight, true},
+		{"mirror top", KleinBottle, SquareGrid{}, Point{X: 1, Y: 0}, DirectionUp, Point{X: 3, Y: 3}, DirectionUp, true},
+		{"mirror left", EdgeTopology{Left: EdgeMirror}, SquareGrid{}, Point{X: 0, Y: 1}, DirectionLeft, Point{X: 4, Y: 2}, DirectionLeft, true},
+		{"klein wraps sideways", KleinBottle, SquareGrid{}, Point{X: 4, Y: 2}, DirectionRight, Point{X: 0, Y: 2}, DirectionRight, true},
+		{"bounce in a corner", Bouncing, SquareGrid{Diagonal: true}, Point{X: 0, Y: 0}, DirectionUpLeft, Point{X: 1, Y: 1}, DirectionDownRight, true},
+		{"bounce then wrap", EdgeTopology{Top: EdgeBounce, Left: EdgeWrap}, SquareGrid{Diagonal: true}, Point{X: 0, Y: 0}, DirectionUpLeft, Point{X: 4, Y: 1}, DirectionDownLeft, true},
+		{"diagonal into a kill edge", Walled, SquareGrid{Diagonal: true}, Point{X: 4, Y: 2}, DirectionDownRight, Point{X: 4, Y: 2}, DirectionDownRight, false},
+		{"bounce into a kill edge", EdgeTopology{Top: EdgeBounce, Left: EdgeKill}, SquareGrid{Diagonal: true}, Point{X: 0, Y: 0}, DirectionUpLeft, Point{X: 0, Y: 0}, DirectionUpLeft, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			to, heading, ok := test.topology.Step(test.grid, test.from, test.direction, hight, width)
+			if to != test.to || heading != test.heading || ok != test.ok {
+				t.Errorf("Step() = %v, %v, %v, want %v, %v, %v", to, heading, ok, test.to, test.heading, test.ok)
+			}
+		})
+	}
+}
+
+func TestParseEdgeTopology(t *testing.T) {
+	tests := []struct {
+		name string
+		want EdgeTopology
+		ok   bool
+	}{
+		{"torus", Torus, true},
+		{"walled", Walled, true},
+		{"klein", KleinBottle, true},
+		{"wrap, kill,bounce,mirror", EdgeTopology{Top: EdgeWrap, Right: EdgeKill, Bottom: EdgeBounce, Left: EdgeMirror}, true},
+		{"wrap,kill", EdgeTopology{}, false},
+		{"wrap,kill,bounce,spin", EdgeTopology{}, false},
+	}
+	for _, test := range tests {
+		got, err := ParseEdgeTopology(test.name)
+		if (err == nil) != test.ok || got != test.want {
+			t.Errorf("ParseEdgeTopology(%q) = %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+}
+
+// Hex boards are accepted exactly when every step can be taken back by turning around
+func TestCheckGridHex(t *testing.T) {
+	rules := []EdgeRule{EdgeWrap, EdgeKill, EdgeMirror}
+	grid := HexGrid{}
+	for _, vertical := range rules {
+		for _, horizontal := range rules {
+			topology := EdgeTopology{Top: vertical, Right: horizontal, Bottom: vertical, Left: horizontal}
+			for _, hight := range []int{5, 6} {
+				symmetric := true
+				for y := 0; y < hight; y++ {
+					for x := 0; x < 6; x++ {
+						p := Point{X: x, Y: y}
+						for _, d := range grid.Directions() {
+							q, heading, ok := topology.Step(grid, p, d, hight, 6)
+							if !ok {
+								continue
+							}
+							r, _, ok := topology.Step(grid, q, oppositeDirections[heading], hight, 6)
+							symmetric = symmetric && ok && r == p
+						}
+					}
+				}
+				if err := checkGrid(grid, topology, hight); (err == nil) != symmetric {
+					t.Errorf("checkGrid(%v, %d) = %v, steps can be taken back: %v", topology, hight, err, symmetric)
+				}
+			}
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package snakegame
 
+import (
+	"fmt"
+	"time"
+)
+
 type Cell int8
 
 const (
@@ -7,6 +12,20 @@ const (
 	CellFood
 	CellSnakeHead
 	CellSnakeTail
+	CellPortal
+	CellObstacle
+	CellEnemy
+	CellPowerUpGhost
+	CellPowerUpSlowMotion
+	CellPowerUpMagnet
+	CellPowerUpDoubler
+	CellWall
+	CellWarning
+	CellRivalHead
+	CellRivalTail
+	CellExit
+	CellGhostHead
+	CellGhostTail
 )
 
 type Direction int8
@@ -16,19 +35,115 @@ const (
 	DirectionRight
 	DirectionDown
 	DirectionLeft
+	DirectionUpRight
+	DirectionDownRight
+	DirectionDownLeft
+	DirectionUpLeft
+)
+
+type CommandKind int8
+
+const (
+	CommandTurn CommandKind = iota
+	CommandQuit
+	CommandRewind
+	CommandResume
+)
+
+// Player command sent by the key handler
+type Command struct {
+	Kind      CommandKind
+	Direction Direction
+	// Index of the player turning in multiplayer games
+	Player int
+}
+
+// Turn the snake in the direction
+func Turn(direction Direction) Command {
+	return Command{Kind: CommandTurn, Direction: direction}
+}
+
+type Outcome int8
+
+const (
+	OutcomeQuit Outcome = iota
+	OutcomeGameOver
+	OutcomeWon
+	// The game mode ran out of time or moves
+	OutcomeTimeUp
+	// Crashed together with the last rivals
+	OutcomeDraw
 )
 
-type DisplayFunc func(board [][]Cell, score int)
-type KeyHandlerFunc func(quit chan bool, turn chan Direction)
+var outcomeNames = []string{"quit", "game over", "won", "time up", "draw"}
 
-// Board structure
+func (o Outcome) String() string {
+	if o < 0 || int(o) >= len(outcomeNames) {
+		return fmt.Sprintf("Outcome(%d)", o)
+	}
+	return outcomeNames[o]
+}
+
+// Final state of the game returned from Run
+type Result struct {
+	Score   int
+	Outcome Outcome
+	// Event the game was won by, e.g. EventExit
+	WonBy EventKind
+}
+
+// Game state shown next to the board
+type HUD struct {
+	Effects []ActiveEffect
+	// Countdown of timed modes, zero otherwise
+	TimeLeft time.Duration
+	// Moves left in move-limited modes, zero otherwise
+	MovesLeft int
+	// Every player of multiplayer games
+	Players []Standing
+	// The snake crashed and waits for a rewind
+	Crashed bool
+	// Racing against a ghost run and the score lead over it
+	Ghost      bool
+	GhostDelta int
+	// Short notifications added by the display wrappers, e.g. unlocked achievements
+	Toasts []string
+}
+
+// Power-up effect in progress with the number of ticks left
+type ActiveEffect struct {
+	Kind      PowerUpKind `json:"kind"`
+	Remaining int         `json:"remaining"`
+}
+
+type DisplayFunc func(board [][]Cell, score int, hud HUD)
+type KeyHandlerFunc func(commands chan<- Command)
+
+// Side of a square block of board cells allocated at once
+const chunkSize = 64
+
+// Block of board cells with the number of non-empty ones
+type chunk struct {
+	cells [chunkSize * chunkSize]Cell
+	used  int
+}
+
+// Board structure, cells are allocated by chunks on demand and
+// only the visible viewport is materialized as a matrix
 type board struct {
-	hight  uint8
-	width  uint8
-	matrix [][]Cell
+	hight  int
+	width  int
+	chunks map[Point]*chunk
+	used   int
+
+	viewportOrigin Point
+	matrix         [][]Cell
+	// Keep the viewport origin on an even row, hex rows alternate their shift
+	alignRows bool
 }
 
-// Stores coordinates
-type vertex struct {
-	x, y uint8
+// Board coordinates
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
 }
@@ -0,0 +1,108 @@
+package snakegame
+
+// Number of border rings between the point and the closest board edge
+func (b *board) depth(p Point) int {
+	return min(p.X, p.Y, b.width-1-p.X, b.hight-1-p.Y)
+}
+
+// Cells of the ring lying depth cells away from the board edges
+func (b *board) ring(depth int) []Point {
+	left, top, right, bottom := depth, depth, b.width-1-depth, b.hight-1-depth
+	if left > right || top > bottom {
+		return nil
+	}
+
+	var points []Point
+	for x := left; x <= right; x++ {
+		points = append(points, Point{X: x, Y: top})
+		if bottom != top {
+			points = append(points, Point{X: x, Y: bottom})
+		}
+	}
+	for y := top + 1; y < bottom; y++ {
+		points = append(points, Point{X: left, Y: y})
+		if right != left {
+			points = append(points, Point{X: right, Y: y})
+		}
+	}
+	return points
+}
+
+// Number of border rings outside of the safe zone, including the one collapsing next
+func (game *SnakeGame) safeMargin() int {
+	if game.nextCollapse > 0 {
+		return game.collapsed + 1
+	}
+	return game.collapsed
+}
+
+// Check if the point stays playable after the scheduled collapse
+func (game *SnakeGame) inSafeZone(p Point) bool {
+	return game.board.depth(p) >= game.safeMargin()
+}
+
+// Check if the safe zone is smaller than the board
+func (game *SnakeGame) zoneLimited() bool {
+	return game.safeMargin() > 0
+}
+
+// Collapse the next ring when its time comes
+func (game *SnakeGame) tickZone() {
+	if game.nextCollapse > 0 && game.tick >= game.nextCollapse {
+		game.nextCollapse = 0
+		game.collapseRing()
+	}
+}
+
+// Turn the outermost playable ring into walls, the snake caught on it dies.
+// The cells of a snake surviving it become walls once vacated
+func (game *SnakeGame) collapseRing() {
+	ring := game.collapsed
+	game.collapsed++
+
+	foodLost := false
+	for _, p := range game.board.ring(ring) {
+		switch game.board.get(p) {
+		case CellSnakeHead, CellSnakeTail:
+			if game.collide(CollisionWall, p, true) {
+				game.gameOver = true
+			}
+			continue
+		case CellRivalHead, CellRivalTail:
+			game.crashRivalAt(p, CollisionWall)
+			continue
+		case CellFood:
+			foodLost = true
+		case CellPortal:
+			exit := game.portals[p]
+			delete(game.portals, p)
+			delete(game.portals, exit)
+			game.board.set(exit, CellEmpty)
+		}
+		if game.powerUp != nil && game.powerUp.At == p {
+			game.powerUp = nil
+		}
+		game.board.set(p, CellWall)
+	}
+
+	if foodLost {
+		game.refillFood()
+	}
+}
+
+// Draw the warning over the empty cells of the ring collapsing next
+func (game *SnakeGame) drawTelegraph() {
+	if game.nextCollapse == 0 || game.nextCollapse-game.tick > game.collapseWarning {
+		return
+	}
+
+	b := &game.board
+	for y := range b.matrix {
+		for x := range b.matrix[y] {
+			p := Point{X: b.viewportOrigin.X + x, Y: b.viewportOrigin.Y + y}
+			if b.depth(p) == game.collapsed && b.get(p) == CellEmpty {
+				b.matrix[y][x] = CellWarning
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package snakegame
+
+import (
+	"testing"
+)
+
+// Rule letting the snake survive walls
+type wallProof struct{ BaseRule }
+
+func (wallProof) OnCollision(ctx RuleContext, collision *Collision) {
+	if collision.Kind == CollisionWall {
+		collision.Fatal = false
+	}
+}
+
+// A snake surviving the collapse leaves walls behind, not holes in the ring
+func TestCollapsedRingClosesBehindTheSnake(t *testing.T) {
+	level := &Level{
+		Hight:     10,
+		Width:     16,
+		Topology:  &Walled,
+		Food:      FoodConfig{Strategy: "scripted", Points: []Point{{X: 8, Y: 8}}},
+		Snake:     []Point{{X: 3, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 0}},
+		Direction: DirectionRight,
+	}
+	game := headlessGame(level.Hight, level.Width, WithLevel(level), WithSeed(1),
+		WithMode(Survival{Every: 4}), WithRules(GrowOnEat{Segments: 1}, ScoreOnEat{Points: 1}, wallProof{}))
+	for game.collapsed == 0 {
+		if _, over := game.step(); over {
+			t.Fatal("game over before the collapse")
+		}
+	}
+	if game.snake.headPoint().Y != 0 {
+		t.Fatalf("snake at %v left the ring before the collapse", game.snake.headPoint())
+	}
+
+	game.commands <- Turn(DirectionDown)
+	for i := 0; i < 4; i++ {
+		if _, over := game.step(); over {
+			t.Fatal("game over after the collapse")
+		}
+	}
+	for _, p := range game.board.ring(0) {
+		if cell := game.board.get(p); cell != CellWall {
+			t.Errorf("collapsed ring cell %v is %v", p, cell)
+		}
+	}
+}
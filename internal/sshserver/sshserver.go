@@ -0,0 +1,156 @@
+package sshserver
+
+import (
+	cls "SnakeGameGolang/internal/clearscreen"
+	sg "SnakeGameGolang/internal/snakegame"
+	"SnakeGameGolang/internal/terminal"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Server settings and game settings applied to every session
+type Settings struct {
+	Addr        string
+	HostKeyPath string
+
+	BoardHight   int
+	BoardWidth   int
+	BorderKiller bool
+}
+
+// Accept SSH connections and host a game per session
+func ListenAndServe(settings Settings) error {
+	hostKey, err := loadHostKey(settings.HostKeyPath)
+	if err != nil {
+		return err
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", settings.Addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go handleConn(conn, config, settings)
+	}
+}
+
+// Load the host key or generate a new one if the file doesn't exist
+func loadHostKey(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(data)
+}
+
+// Perform the handshake and serve session channels
+func handleConn(conn net.Conn, config *ssh.ServerConfig, settings Settings) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		log.Printf("ssh handshake with %s failed: %v", conn.RemoteAddr(), err)
+		return
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			log.Printf("ssh channel from %s failed: %v", conn.RemoteAddr(), err)
+			continue
+		}
+		go handleSession(channel, channelRequests, settings)
+	}
+}
+
+// Handle PTY and shell requests, run the game once the shell is requested
+func handleSession(channel ssh.Channel, requests <-chan *ssh.Request, settings Settings) {
+	renderer := terminal.NewRenderer(channel, cls.ClearANSI, "\r\n")
+	started := false
+
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			renderer.Resize(parsePtyRequest(req.Payload))
+			req.Reply(true, nil)
+		case "window-change":
+			renderer.Resize(parseWindowChange(req.Payload))
+		case "shell":
+			req.Reply(!started, nil)
+			if !started {
+				started = true
+				go play(channel, renderer, settings)
+			}
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// Run a single game and close the session
+func play(channel ssh.Channel, renderer *terminal.Renderer, settings Settings) {
+	defer channel.Close()
+
+	snakeGame := sg.SnakeGame{}
+	snakeGame.Init(settings.BoardHight, settings.BoardWidth, settings.BorderKiller, renderer.Display, terminal.KeyHandler(channel))
+	result := snakeGame.Run()
+
+	renderer.GameOver(result)
+	channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+}
+
+// Extract terminal dimensions from "pty-req" payload: term, cols, rows, ...
+func parsePtyRequest(payload []byte) (cols, rows int) {
+	if len(payload) < 4 {
+		return 0, 0
+	}
+	termLen := int(binary.BigEndian.Uint32(payload))
+	if termLen > len(payload)-4 {
+		return 0, 0
+	}
+	return parseWindowChange(payload[4+termLen:])
+}
+
+// Extract terminal dimensions from "window-change" payload: cols, rows, ...
+func parseWindowChange(payload []byte) (cols, rows int) {
+	if len(payload) < 8 {
+		return 0, 0
+	}
+	return int(binary.BigEndian.Uint32(payload)), int(binary.BigEndian.Uint32(payload[4:]))
+}
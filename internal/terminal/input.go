@@ -0,0 +1,98 @@
+package terminal
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"io"
+	"time"
+)
+
+const (
+	keyCtrlC     = 0x03
+	keyBackspace = 0x7f
+	keyEsc       = 0x1b
+)
+
+var arrowDirections = map[byte]sg.Direction{
+	'A': sg.DirectionUp,
+	'C': sg.DirectionRight,
+	'B': sg.DirectionDown,
+	'D': sg.DirectionLeft,
+}
+
+// Time to wait for the rest of an escape sequence split across reads
+// before taking the escape as a key press of its own
+const escapeTimeout = 50 * time.Millisecond
+
+// Create key handler parsing raw terminal input from the reader
+func KeyHandler(in io.Reader) sg.KeyHandlerFunc {
+	return func(commands chan<- sg.Command) {
+		done := make(chan struct{})
+		defer close(done)
+		chunks := make(chan []byte)
+		go func() {
+			defer close(chunks)
+			for {
+				buf := make([]byte, 64)
+				n, err := in.Read(buf)
+				if err != nil {
+					return
+				}
+				select {
+				case chunks <- buf[:n]:
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		var pending []byte
+		for {
+			var timeout <-chan time.Time
+			if len(pending) > 0 {
+				timeout = time.After(escapeTimeout)
+			}
+			var buf []byte
+			select {
+			case chunk, ok := <-chunks:
+				if !ok {
+					commands <- sg.Command{Kind: sg.CommandQuit}
+					return
+				}
+				buf = append(pending, chunk...)
+			case <-timeout:
+				// Lone escape press, a cut sequence is dropped
+				if len(pending) == 1 {
+					commands <- sg.Command{Kind: sg.CommandQuit}
+					return
+				}
+			}
+			pending = nil
+
+			n := len(buf)
+			for i := 0; i < n; i++ {
+				switch {
+				case buf[i] == keyCtrlC || buf[i] == 'q':
+					commands <- sg.Command{Kind: sg.CommandQuit}
+					return
+
+				case buf[i] == keyBackspace:
+					commands <- sg.Command{Kind: sg.CommandRewind}
+				case buf[i] == ' ':
+					commands <- sg.Command{Kind: sg.CommandResume}
+
+				// Escape or the start of a sequence at the end of the read, wait for more
+				case buf[i] == keyEsc && (i+1 == n || i+2 == n && (buf[i+1] == '[' || buf[i+1] == 'O')):
+					pending = append([]byte(nil), buf[i:]...)
+					i = n
+
+				// Arrow keys in normal and application cursor modes
+				case buf[i] == keyEsc && (buf[i+1] == '[' || buf[i+1] == 'O'):
+					if direction, ok := arrowDirections[buf[i+2]]; ok {
+						commands <- sg.Turn(direction)
+					}
+					i += 2
+				}
+			}
+		}
+	}
+}
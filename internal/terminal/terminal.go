@@ -0,0 +1,273 @@
+package terminal
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Cell symbols of the default theme
+var cellSymbols = map[sg.Cell]string{
+	sg.CellEmpty:     "_",
+	sg.CellFood:      "$",
+	sg.CellSnakeHead: "%",
+	sg.CellSnakeTail: "*",
+	sg.CellPortal:    "O",
+	sg.CellObstacle:  "#",
+	sg.CellEnemy:     "@",
+
+	sg.CellPowerUpGhost:      "G",
+	sg.CellPowerUpSlowMotion: "S",
+	sg.CellPowerUpMagnet:     "M",
+	sg.CellPowerUpDoubler:    "2",
+
+	sg.CellWall:    "X",
+	sg.CellWarning: "!",
+	sg.CellExit:    "E",
+
+	sg.CellRivalHead: "&",
+	sg.CellRivalTail: "+",
+
+	// Dim the ghost snake
+	sg.CellGhostHead: "\x1b[2m%\x1b[0m",
+	sg.CellGhostTail: "\x1b[2m*\x1b[0m",
+}
+
+// Cell symbols per theme name, missing cells fall back to the default theme
+var Themes = map[string]map[sg.Cell]string{
+	"classic": cellSymbols,
+	"blocks": {
+		sg.CellEmpty:     "\u00b7",
+		sg.CellFood:      "\u25cf",
+		sg.CellSnakeHead: "\u2588",
+		sg.CellSnakeTail: "\u2593",
+		sg.CellPortal:    "\u25ce",
+		sg.CellObstacle:  "\u2592",
+		sg.CellEnemy:     "\u25c6",
+		sg.CellWall:      "\u2591",
+		sg.CellRivalHead: "\u25a0",
+		sg.CellRivalTail: "\u25a1",
+		sg.CellGhostHead: "\x1b[2m\u2588\x1b[0m",
+		sg.CellGhostTail: "\x1b[2m\u2593\x1b[0m",
+	},
+	"color": {
+		sg.CellFood:      "\x1b[33m$\x1b[0m",
+		sg.CellSnakeHead: "\x1b[1;32m%\x1b[0m",
+		sg.CellSnakeTail: "\x1b[32m*\x1b[0m",
+		sg.CellEnemy:     "\x1b[31m@\x1b[0m",
+		sg.CellWall:      "\x1b[34mX\x1b[0m",
+		sg.CellWarning:   "\x1b[31m!\x1b[0m",
+		sg.CellRivalHead: "\x1b[1;35m&\x1b[0m",
+		sg.CellRivalTail: "\x1b[35m+\x1b[0m",
+	},
+}
+
+// Text renderer writing boards to an arbitrary output
+type Renderer struct {
+	Out     io.Writer
+	Clear   func(w io.Writer)
+	Newline string
+	// Draw a hex grid: cells are spaced out and odd rows shifted by half a cell
+	Hex bool
+
+	mu         sync.Mutex
+	cols, rows int
+	theme      map[sg.Cell]string
+}
+
+// Create renderer for the given output
+func NewRenderer(out io.Writer, clear func(w io.Writer), newline string) *Renderer {
+	return &Renderer{Out: out, Clear: clear, Newline: newline}
+}
+
+// Draw the cells with the symbols of the named theme, empty name selects the default one
+func (r *Renderer) SetTheme(name string) error {
+	if name == "" {
+		name = "classic"
+	}
+	theme, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.theme = theme
+	return nil
+}
+
+// Lay the boards out on the grid, hex cells are spaced out
+func (r *Renderer) UseGrid(grid sg.Grid) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, r.Hex = grid.(sg.HexGrid)
+}
+
+// Update terminal dimensions, zero values disable the size check
+func (r *Renderer) Resize(cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cols, r.rows = cols, rows
+}
+
+// Draw board, score and active effects, matches sg.DisplayFunc
+func (r *Renderer) Display(board [][]sg.Cell, score int, hud sg.HUD) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\t<Score: %d>", score)
+	if hud.TimeLeft > 0 {
+		fmt.Fprintf(&buf, " <Time: %s>", hud.TimeLeft.Round(time.Second/10))
+	}
+	if hud.MovesLeft > 0 {
+		fmt.Fprintf(&buf, " <Moves: %d>", hud.MovesLeft)
+	}
+	for i, player := range hud.Players {
+		if player.Alive {
+			fmt.Fprintf(&buf, " [P%d %d]", i+1, player.Score)
+		} else {
+			fmt.Fprintf(&buf, " [P%d %d x]", i+1, player.Score)
+		}
+	}
+	for _, effect := range hud.Effects {
+		fmt.Fprintf(&buf, " [%s %d]", effect.Kind, effect.Remaining)
+	}
+	if hud.Ghost {
+		fmt.Fprintf(&buf, " <Ghost: %+d>", hud.GhostDelta)
+	}
+	if hud.Crashed {
+		buf.WriteString(" <Crashed: Backspace to rewind, Esc to quit>")
+	}
+	for _, toast := range hud.Toasts {
+		fmt.Fprintf(&buf, " <%s>", toast)
+	}
+	buf.WriteString(r.Newline)
+	if r.tooSmall(board) {
+		fmt.Fprintf(&buf, "Terminal is too small, resize to at least %dx%d%s", r.boardCols(board), len(board)+1, r.Newline)
+	} else {
+		for hight := range board {
+			if r.Hex && hight%2 == 1 {
+				buf.WriteString(" ")
+			}
+			for widht := range board[hight] {
+				if r.Hex && widht > 0 {
+					buf.WriteString(" ")
+				}
+				buf.WriteString(r.symbol(board[hight][widht]))
+			}
+			buf.WriteString(r.Newline)
+		}
+	}
+
+	r.Clear(r.Out)
+	r.Out.Write(buf.Bytes())
+}
+
+// Draw the level editor board with the cell under the cursor in reverse video
+func (r *Renderer) Editor(board [][]sg.Cell, cursor sg.Point, status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\t<Editor: %d,%d>%s", cursor.X, cursor.Y, r.Newline)
+	for hight := range board {
+		for widht := range board[hight] {
+			symbol := r.symbol(board[hight][widht])
+			if hight == cursor.Y && widht == cursor.X {
+				symbol = "\x1b[7m" + symbol + "\x1b[0m"
+			}
+			buf.WriteString(symbol)
+		}
+		buf.WriteString(r.Newline)
+	}
+	buf.WriteString("W wall, P portal, S spawn, F food, E exit, X erase, T test-play, Ctrl+S save, Esc quit" + r.Newline)
+	buf.WriteString(status + r.Newline)
+
+	r.Clear(r.Out)
+	r.Out.Write(buf.Bytes())
+}
+
+// Draw the final score
+func (r *Renderer) GameOver(result sg.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Clear(r.Out)
+	fmt.Fprintf(r.Out, "<< Score: %d >>%s", result.Score, r.Newline)
+	switch result.Outcome {
+	case sg.OutcomeWon:
+		fmt.Fprintf(r.Out, "%s, you win!%s", winMessages[result.WonBy], r.Newline)
+	case sg.OutcomeTimeUp:
+		fmt.Fprintf(r.Out, "Time is up!%s", r.Newline)
+	}
+}
+
+// What the player did to win, by the winning event
+var winMessages = map[sg.EventKind]string{
+	sg.EventCleared:  "Board cleared",
+	sg.EventAllEaten: "All the food eaten",
+	sg.EventExit:     "Exit reached",
+	sg.EventOutlived: "Last one standing",
+}
+
+// Draw the end of a puzzle
+func (r *Renderer) PuzzleOver(result sg.Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Clear(r.Out)
+	switch result.Outcome {
+	case sg.OutcomeWon:
+		fmt.Fprintf(r.Out, "Puzzle solved!%s", r.Newline)
+	case sg.OutcomeTimeUp:
+		fmt.Fprintf(r.Out, "Out of moves!%s", r.Newline)
+	case sg.OutcomeGameOver:
+		fmt.Fprintf(r.Out, "Crashed!%s", r.Newline)
+	}
+}
+
+// Draw the final light-cycle standings
+func (r *Renderer) CyclesOver(result sg.Result, standings []sg.Standing) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Clear(r.Out)
+	switch result.Outcome {
+	case sg.OutcomeWon:
+		fmt.Fprintf(r.Out, "Last one standing, you win!%s", r.Newline)
+	case sg.OutcomeDraw:
+		fmt.Fprintf(r.Out, "Crashed together, it's a draw!%s", r.Newline)
+	case sg.OutcomeGameOver:
+		fmt.Fprintf(r.Out, "Crashed!%s", r.Newline)
+	}
+	for i, standing := range standings {
+		fmt.Fprintf(r.Out, "P%d: %d%s", i+1, standing.Score, r.Newline)
+	}
+}
+
+// Check if the board doesn't fit the known terminal size
+func (r *Renderer) tooSmall(board [][]sg.Cell) bool {
+	if r.cols == 0 || r.rows == 0 || len(board) == 0 {
+		return false
+	}
+	return r.boardCols(board) > r.cols || len(board)+1 > r.rows
+}
+
+// Number of terminal columns taken by the board
+func (r *Renderer) boardCols(board [][]sg.Cell) int {
+	if r.Hex {
+		return 2 * len(board[0])
+	}
+	return len(board[0])
+}
+
+// Symbol of the cell in the current theme
+func (r *Renderer) symbol(cell sg.Cell) string {
+	if symbol, ok := r.theme[cell]; ok {
+		return symbol
+	}
+	return cellSymbols[cell]
+}
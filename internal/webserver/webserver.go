@@ -0,0 +1,124 @@
+package webserver
+
+import (
+	sg "SnakeGameGolang/internal/snakegame"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+)
+
+//go:embed static
+var static embed.FS
+
+// Game settings applied to every browser session
+type Settings struct {
+	BoardHight   int
+	BoardWidth   int
+	BorderKiller bool
+}
+
+// Frame streamed to the browser client
+type frame struct {
+	Board    [][]sg.Cell       `json:"board"`
+	Score    int               `json:"score"`
+	Effects  []sg.ActiveEffect `json:"effects,omitempty"`
+	TimeLeft float64           `json:"timeLeft,omitempty"`
+	GameOver bool              `json:"gameOver"`
+	Won      bool              `json:"won"`
+	TimeUp   bool              `json:"timeUp,omitempty"`
+}
+
+// Command received from the browser client
+type command struct {
+	Turn string `json:"turn"`
+	Quit bool   `json:"quit"`
+}
+
+var directions = map[string]sg.Direction{
+	"up":    sg.DirectionUp,
+	"right": sg.DirectionRight,
+	"down":  sg.DirectionDown,
+	"left":  sg.DirectionLeft,
+}
+
+// Serve the embedded client and host a game per WebSocket connection
+func ListenAndServe(addr string, settings Settings) error {
+	return http.ListenAndServe(addr, NewHandler(settings))
+}
+
+// Build the HTTP handler with the static client and the game endpoint
+func NewHandler(settings Settings) http.Handler {
+	root, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(root)))
+	mux.Handle("/play", websocket.Server{
+		Handshake: checkOrigin,
+		Handler: func(conn *websocket.Conn) {
+			play(conn, settings)
+		},
+	})
+	return mux
+}
+
+// Accept only the pages served by this host, so other sites can't drive the sessions
+func checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin == nil || origin.Host != req.Host {
+		return fmt.Errorf("origin %v is not allowed", origin)
+	}
+	config.Origin = origin
+	return nil
+}
+
+// Run a single game session over the connection
+func play(conn *websocket.Conn, settings Settings) {
+	done := make(chan struct{})
+	defer close(done)
+	snakeGame := sg.SnakeGame{}
+	snakeGame.Init(settings.BoardHight, settings.BoardWidth, settings.BorderKiller, displayFunc(conn), keyHandlerFunc(conn, done))
+	result := snakeGame.Run()
+
+	_ = websocket.JSON.Send(conn, frame{Score: result.Score, GameOver: true, Won: result.Outcome == sg.OutcomeWon, TimeUp: result.Outcome == sg.OutcomeTimeUp})
+}
+
+// Stream every rendered board as a JSON frame
+func displayFunc(conn *websocket.Conn) sg.DisplayFunc {
+	return func(board [][]sg.Cell, score int, hud sg.HUD) {
+		_ = websocket.JSON.Send(conn, frame{Board: board, Score: score, Effects: hud.Effects, TimeLeft: hud.TimeLeft.Seconds()})
+	}
+}
+
+// Translate client commands into engine signals until the connection or the game ends,
+// nobody reads the commands once the game is done
+func keyHandlerFunc(conn *websocket.Conn, done <-chan struct{}) sg.KeyHandlerFunc {
+	return func(commands chan<- sg.Command) {
+		send := func(command sg.Command) bool {
+			select {
+			case commands <- command:
+				return true
+			case <-done:
+				return false
+			}
+		}
+		for {
+			var cmd command
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil || cmd.Quit {
+				send(sg.Command{Kind: sg.CommandQuit})
+				return
+			}
+			if direction, ok := directions[cmd.Turn]; ok && !send(sg.Turn(direction)) {
+				return
+			}
+		}
+	}
+}